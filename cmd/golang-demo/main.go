@@ -0,0 +1,314 @@
+// Command golang-demo runs the album catalog HTTP service.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	internalhttp "github.com/devchiran/golang-demo/internal/http"
+	internalpostgres "github.com/devchiran/golang-demo/internal/postgres"
+	"github.com/devchiran/golang-demo/tools"
+	httputils "github.com/devchiran/golang-demo/tools/http"
+	"github.com/devchiran/golang-demo/tools/lifecycle"
+	"github.com/devchiran/golang-demo/tools/postgres"
+	"github.com/devchiran/golang-demo/tools/queue"
+	"github.com/devchiran/golang-demo/tools/stats"
+	"github.com/devchiran/golang-demo/tools/zap"
+)
+
+const (
+	serviceName    = "golang-demo"
+	serviceVersion = "dev"
+)
+
+// The real store must keep implementing the handler's AlbumStore
+// contract; a drifted method signature fails compilation here instead
+// of at the wiring below.
+var _ internalhttp.AlbumStore = (*internalpostgres.Postgres)(nil)
+
+type variables struct {
+	Addr string `envconfig:"ADDR" default:":8080"`
+
+	// DatabaseURL, when set, takes precedence over the discrete
+	// POSTGRES_* variables below.
+	DatabaseURL string `envconfig:"DATABASE_URL"`
+
+	PostgresHost       string `envconfig:"POSTGRES_HOST" default:"localhost"`
+	PostgresPort       int    `envconfig:"POSTGRES_PORT" default:"5432"`
+	PostgresUser       string `envconfig:"POSTGRES_USER" default:"postgres"`
+	PostgresPass       string `envconfig:"POSTGRES_PASS" default:"postgres"`
+	PostgresDB         string `envconfig:"POSTGRES_DB" default:"postgres"`
+	PostgresDisableSSL bool   `envconfig:"POSTGRES_DISABLE_SSL" default:"true"`
+
+	// PostgresReplicaHost, when set, opens a second pool for the album
+	// read queries; writes stay on the primary. The replica reuses the
+	// primary's credentials and database, with an optional port override.
+	PostgresReplicaHost string `envconfig:"POSTGRES_REPLICA_HOST"`
+	PostgresReplicaPort int    `envconfig:"POSTGRES_REPLICA_PORT"`
+
+	// QueueBackend selects the queue consumer backend; empty disables
+	// the consumer.
+	QueueBackend string `envconfig:"QUEUE_BACKEND"`
+	QueueWorkers int    `envconfig:"QUEUE_WORKERS" default:"10"`
+
+	// ShutdownTimeout is how long to wait for running processes during
+	// shutdown; TCPKeepAlive is the accepted connections' keep-alive
+	// period.
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	TCPKeepAlive    time.Duration `envconfig:"TCP_KEEPALIVE" default:"30s"`
+
+	// MaxConnections and ConcurrentLimit tune request admission; zero
+	// keeps the handler defaults.
+	MaxConnections  int64 `envconfig:"MAX_CONNECTIONS"`
+	ConcurrentLimit int64 `envconfig:"CONCURRENT_LIMIT"`
+
+	// DebugRoutes exposes GET /debug/routes; leave it unset in
+	// production.
+	DebugRoutes bool `envconfig:"DEBUG_ROUTES"`
+
+	// Metrics exposes GET /metrics for Prometheus scraping; DebugPprof
+	// exposes the runtime profiler under /debug/pprof. Both default off.
+	Metrics    bool `envconfig:"METRICS"`
+	DebugPprof bool `envconfig:"DEBUG_PPROF"`
+
+	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
+}
+
+// String renders the config for startup logging with secret fields
+// masked, so the Postgres password never reaches stdout or the logs.
+func (v variables) String() string {
+	masked := v
+	if masked.PostgresPass != "" {
+		masked.PostgresPass = "****"
+	}
+	if masked.DatabaseURL != "" {
+		masked.DatabaseURL = redactURL(masked.DatabaseURL)
+	}
+	// The local type sheds the String method; formatting the variables
+	// type itself would recurse.
+	type plain variables
+	return fmt.Sprintf("%+v", plain(masked))
+}
+
+// redactURL masks the password in a connection URL. URLs that do not
+// parse are hidden entirely rather than risk echoing a credential.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "****"
+	}
+	return u.Redacted()
+}
+
+// validateAddr checks that addr is a usable listen address, so a typo
+// like "8080" fails at startup instead of surfacing as an opaque bind
+// error later.
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not a host:port address: %v", addr, err)
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("%q is not a valid port", port)
+	}
+	return nil
+}
+
+// shutdownTimings resolves the shutdown and keep-alive durations,
+// falling back to the defaults when the env sets them to zero or
+// negative values.
+func shutdownTimings(v variables) (shutdown, keepAlive time.Duration) {
+	shutdown, keepAlive = v.ShutdownTimeout, v.TCPKeepAlive
+	if shutdown <= 0 {
+		shutdown = 15 * time.Second
+	}
+	if keepAlive <= 0 {
+		keepAlive = 30 * time.Second
+	}
+	return shutdown, keepAlive
+}
+
+var v variables
+
+func init() {
+	if err := envconfig.Process("", &v); err != nil {
+		log.Fatalf("processing env variables: %v", err)
+	}
+	if err := validateAddr(v.Addr); err != nil {
+		log.Fatalf("invalid ADDR: %v", err)
+	}
+}
+
+func main() {
+	logger, err := zap.New(zap.Config{
+		Level:         v.LogLevel,
+		CtxValueFuncs: []zap.CtxValueFunc{zap.RequestIDCtxValueFunc},
+	})
+	if err != nil {
+		log.Fatalf("building logger: %v", err)
+	}
+	defer logger.Sync() //nolint:errcheck
+
+	logger.Info("startup config", "config", v.String())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	lc := lifecycle.New(ctx, logger)
+
+	// The stats client backs both the Postgres query metrics and the
+	// /metrics endpoint; without METRICS neither is wanted, and a nil
+	// client leaves the hooks as no-ops.
+	var sc tools.StatsClient
+	if v.Metrics {
+		sc = stats.New()
+	}
+
+	pg, err := newPostgres(v, sc)
+	if err != nil {
+		log.Fatalf("connecting to postgres: %v", err)
+	}
+	lc.Defer("postgres", pg.Close)
+
+	q, err := newQueue(v)
+	if err != nil {
+		log.Fatalf("configuring queue: %v", err)
+	}
+	if q != nil {
+		startQueueConsumer(lc, q, logger, v.QueueWorkers)
+	}
+
+	// A per-process key is enough for pagination cursors; they are
+	// short-lived and clients re-list from the start after a deploy.
+	cursorKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, cursorKey); err != nil {
+		log.Fatalf("generating cursor key: %v", err)
+	}
+
+	h := &internalhttp.Handler{
+		Logger:          logger,
+		AlbumStore:      pg,
+		Stats:           sc,
+		Name:            serviceName,
+		Version:         serviceVersion,
+		CursorKey:       cursorKey,
+		MaxConnections:  v.MaxConnections,
+		ConcurrentLimit: v.ConcurrentLimit,
+		DebugRoutes:     v.DebugRoutes,
+		Metrics:         v.Metrics,
+		DebugPprof:      v.DebugPprof,
+	}
+
+	// Once shutdown begins, stop admitting new requests; in-flight ones
+	// are still given the Shutdown grace period below.
+	handler := httputils.DrainMiddleware(func() bool {
+		return lc.Context().Err() != nil
+	}, logger, h.Handler().ServeHTTP)
+
+	shutdownTimeout, keepAlive := shutdownTimings(v)
+
+	srv := httputils.NewServer(v.Addr, handler)
+	lc.Start("http server", func() error {
+		logger.Info("http server listening", "addr", v.Addr)
+		if err := httputils.ListenAndServe(srv, keepAlive); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+	lc.Start("http server shutdown", func() error {
+		<-lc.Context().Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	})
+
+	if err := lc.Wait(shutdownTimeout); err != nil {
+		logger.Error("shutdown", "error", err.Error())
+	}
+}
+
+// newQueue selects the backend named by QUEUE_BACKEND. The demo ships
+// no concrete backend; deployments register theirs in this switch.
+func newQueue(v variables) (queue.Queue, error) {
+	switch v.QueueBackend {
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", v.QueueBackend)
+	}
+}
+
+// startQueueConsumer runs a consumer under the lifecycle, so it drains
+// and stops when shutdown begins. The handler just logs and acks; real
+// message handling hangs off it later.
+func startQueueConsumer(lc *lifecycle.LifeCycle, q queue.Queue, logger tools.LoggerCtx, workers int) {
+	consumer := queue.NewConsumer(q,
+		queue.WithNumWorkers(workers),
+		queue.WithLogger(logger),
+	)
+	handler := queue.HandlerFunc(func(ctx context.Context, m queue.Message) error {
+		logger.InfoCtx(ctx, "queue message received", "message_id", m.ID)
+		return nil // a nil return acks the message
+	})
+	lc.Start("queue consumer", func() error {
+		err := consumer.Consume(lc.Context(), handler)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	})
+}
+
+func newPostgres(v variables, sc tools.StatsClient) (*internalpostgres.Postgres, error) {
+	opts := postgres.Options{
+		Host:       v.PostgresHost,
+		Port:       v.PostgresPort,
+		User:       v.PostgresUser,
+		Password:   v.PostgresPass,
+		DBName:     v.PostgresDB,
+		DisableSSL: v.PostgresDisableSSL,
+	}
+	if v.DatabaseURL != "" {
+		var err error
+		opts, err = postgres.ParseURL(v.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	db, err := postgres.New(opts,
+		postgres.WithOnComplete(postgres.StatsOnComplete(sc, "postgres")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var storeOpts []internalpostgres.Option
+	if v.PostgresReplicaHost != "" {
+		replicaOpts := opts
+		replicaOpts.Host = v.PostgresReplicaHost
+		if v.PostgresReplicaPort != 0 {
+			replicaOpts.Port = v.PostgresReplicaPort
+		}
+		replica, err := postgres.New(replicaOpts,
+			postgres.WithOnComplete(postgres.StatsOnComplete(sc, "postgres_replica")),
+		)
+		if err != nil {
+			return nil, err
+		}
+		storeOpts = append(storeOpts, internalpostgres.WithReplica(replica))
+	}
+	// The listener powers the album change stream; it shares the store's
+	// lifetime and is closed with it.
+	return internalpostgres.NewWithListener(db, postgres.NewListener(opts), storeOpts...), nil
+}
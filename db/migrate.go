@@ -0,0 +1,99 @@
+// Command migrate applies the SQL migrations in db/migrations to the
+// database named by DATABASE_URL.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// migrator is the subset of *migrate.Migrate the commands dispatch to,
+// kept narrow so the dispatch can be tested without a database.
+type migrator interface {
+	Up() error
+	Down() error
+	Steps(n int) error
+	Force(version int) error
+	Version() (uint, bool, error)
+}
+
+func main() {
+	var (
+		source  = flag.String("source", "file://db/migrations", "migration source URL")
+		dbURL   = flag.String("database", os.Getenv("DATABASE_URL"), "database URL")
+		command = flag.String("command", "up", "one of up, down, version, force=N, steps=N")
+	)
+	flag.Parse()
+
+	m, err := migrate.New(*source, *dbURL)
+	if err != nil {
+		log.Fatalf("opening migrator: %v", err)
+	}
+	if err := runCommand(m, *command); err != nil {
+		log.Fatalf("running %s: %v", *command, err)
+	}
+	version, dirty, err := m.Version()
+	if err == migrate.ErrNilVersion {
+		log.Println("no migrations applied")
+		return
+	}
+	if err != nil {
+		log.Fatalf("reading version: %v", err)
+	}
+	log.Printf("at version %d (dirty: %v)", version, dirty)
+}
+
+// runCommand dispatches command to the matching migrator call. A
+// migration run that finds nothing to do is not an error.
+func runCommand(m migrator, command string) error {
+	name, arg, hasArg := strings.Cut(command, "=")
+	switch name {
+	case "up":
+		return ignoreNoChange(m.Up())
+	case "down":
+		return ignoreNoChange(m.Down())
+	case "version":
+		// The version is printed after every command; nothing to run.
+		return nil
+	case "force":
+		v, err := commandArg(name, arg, hasArg)
+		if err != nil {
+			return err
+		}
+		return m.Force(v)
+	case "steps":
+		n, err := commandArg(name, arg, hasArg)
+		if err != nil {
+			return err
+		}
+		return ignoreNoChange(m.Steps(n))
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func commandArg(name, arg string, hasArg bool) (int, error) {
+	if !hasArg {
+		return 0, fmt.Errorf("%s requires an argument, e.g. %s=2", name, name)
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%s argument %q is not an integer", name, arg)
+	}
+	return n, nil
+}
+
+func ignoreNoChange(err error) error {
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// fakeMigrator records which migrator method ran and with what argument.
+type fakeMigrator struct {
+	calls []string
+	arg   int
+	err   error
+}
+
+func (m *fakeMigrator) Up() error   { m.calls = append(m.calls, "up"); return m.err }
+func (m *fakeMigrator) Down() error { m.calls = append(m.calls, "down"); return m.err }
+
+func (m *fakeMigrator) Steps(n int) error {
+	m.calls = append(m.calls, "steps")
+	m.arg = n
+	return m.err
+}
+
+func (m *fakeMigrator) Force(version int) error {
+	m.calls = append(m.calls, "force")
+	m.arg = version
+	return m.err
+}
+
+func (m *fakeMigrator) Version() (uint, bool, error) { return 0, false, nil }
+
+func TestRunCommandDispatch(t *testing.T) {
+	for _, tc := range []struct {
+		command  string
+		wantCall string
+		wantArg  int
+	}{
+		{command: "up", wantCall: "up"},
+		{command: "down", wantCall: "down"},
+		{command: "steps=3", wantCall: "steps", wantArg: 3},
+		{command: "steps=-2", wantCall: "steps", wantArg: -2},
+		{command: "force=7", wantCall: "force", wantArg: 7},
+	} {
+		t.Run(tc.command, func(t *testing.T) {
+			m := &fakeMigrator{}
+			if err := runCommand(m, tc.command); err != nil {
+				t.Fatalf("runCommand: %v", err)
+			}
+			if len(m.calls) != 1 || m.calls[0] != tc.wantCall {
+				t.Errorf("calls = %v, want [%s]", m.calls, tc.wantCall)
+			}
+			if m.arg != tc.wantArg {
+				t.Errorf("arg = %d, want %d", m.arg, tc.wantArg)
+			}
+		})
+	}
+}
+
+func TestRunCommandVersionRunsNothing(t *testing.T) {
+	m := &fakeMigrator{}
+	if err := runCommand(m, "version"); err != nil {
+		t.Fatalf("runCommand: %v", err)
+	}
+	if len(m.calls) != 0 {
+		t.Errorf("calls = %v, want none", m.calls)
+	}
+}
+
+func TestRunCommandNoChangeIsNotAnError(t *testing.T) {
+	m := &fakeMigrator{err: migrate.ErrNoChange}
+	if err := runCommand(m, "up"); err != nil {
+		t.Errorf("up with no pending migrations: %v", err)
+	}
+}
+
+func TestRunCommandRejectsBadInput(t *testing.T) {
+	for _, command := range []string{"sideways", "steps", "steps=many", "force"} {
+		t.Run(command, func(t *testing.T) {
+			m := &fakeMigrator{}
+			if err := runCommand(m, command); err == nil {
+				t.Error("expected an error")
+			}
+			if len(m.calls) != 0 {
+				t.Errorf("calls = %v, want none", m.calls)
+			}
+		})
+	}
+}
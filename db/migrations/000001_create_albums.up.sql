@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS albums (
+    id uuid PRIMARY KEY,
+    title text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
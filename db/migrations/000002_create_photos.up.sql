@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS photos (
+    id uuid PRIMARY KEY,
+    album_id uuid NOT NULL REFERENCES albums (id) ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS photos_album_id_idx ON photos (album_id);
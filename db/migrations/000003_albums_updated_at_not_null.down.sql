@@ -0,0 +1,2 @@
+ALTER TABLE albums ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE albums ALTER COLUMN updated_at DROP DEFAULT;
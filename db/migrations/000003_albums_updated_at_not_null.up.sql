@@ -0,0 +1,4 @@
+UPDATE albums SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE albums ALTER COLUMN updated_at SET DEFAULT now();
+ALTER TABLE albums ALTER COLUMN updated_at SET NOT NULL;
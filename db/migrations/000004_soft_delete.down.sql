@@ -0,0 +1,2 @@
+ALTER TABLE albums DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE photos DROP COLUMN IF EXISTS deleted_at;
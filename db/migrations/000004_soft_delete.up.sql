@@ -0,0 +1,7 @@
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- Soft-deleted rows keep occupying any plain unique index. If a unique
+-- constraint is ever added (e.g. on albums.title), make it a partial
+-- index over live rows so a deleted row does not block re-creation:
+--   CREATE UNIQUE INDEX ... ON albums (title) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE albums
+    DROP COLUMN IF EXISTS cover_photo_id;
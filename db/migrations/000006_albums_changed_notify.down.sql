@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS albums_changed_notify ON albums;
+DROP FUNCTION IF EXISTS notify_albums_changed();
@@ -0,0 +1,11 @@
+CREATE OR REPLACE FUNCTION notify_albums_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('albums_changed', COALESCE(NEW.id, OLD.id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS albums_changed_notify ON albums;
+CREATE TRIGGER albums_changed_notify
+    AFTER INSERT OR UPDATE OR DELETE ON albums
+    FOR EACH ROW EXECUTE FUNCTION notify_albums_changed();
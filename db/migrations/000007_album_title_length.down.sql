@@ -0,0 +1 @@
+ALTER TABLE albums DROP CONSTRAINT IF EXISTS albums_title_length;
@@ -0,0 +1,4 @@
+-- Defense in depth for the handler-side title validation: no row may
+-- carry a title longer than 255 characters.
+ALTER TABLE albums
+    ADD CONSTRAINT albums_title_length CHECK (char_length(title) <= 255);
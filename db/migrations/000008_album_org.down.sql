@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS albums_org_id_idx;
+ALTER TABLE albums DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,5 @@
+-- Albums belong to an organization. Pre-existing rows keep a NULL
+-- org_id and stay visible only to callers without an organization.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS org_id text;
+
+CREATE INDEX IF NOT EXISTS albums_org_id_idx ON albums (org_id);
@@ -0,0 +1,143 @@
+// Package catelog defines the domain types for the album catalog
+// service.
+package catelog
+
+import (
+	"gopkg.in/guregu/null.v4"
+)
+
+// Album is a photo album as stored and served by the API.
+type Album struct {
+	ID        string `db:"id" json:"id"`
+	Title     string `db:"title" json:"title"`
+	CreatedAt Time   `db:"created_at" json:"created_at"`
+	UpdatedAt Time   `db:"updated_at" json:"updated_at"`
+	// PhotoCount is an aggregate computed by the store, not a
+	// persisted column.
+	PhotoCount int `db:"photo_count" json:"photo_count"`
+	// DeletedAt is set when the album was soft-deleted. Only queries
+	// that explicitly include deleted rows ever return it non-null.
+	DeletedAt null.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	// CoverPhotoID points at the photo chosen as the album cover, if
+	// any. It always references a photo within the album.
+	CoverPhotoID null.String `db:"cover_photo_id" json:"cover_photo_id,omitempty"`
+	// OrgID is the owning organization. Albums created before
+	// multi-tenancy carry null and belong to no organization.
+	OrgID null.String `db:"org_id" json:"org_id,omitempty"`
+}
+
+// CreateAlbumRequest is the JSON body accepted by POST /v1/album.
+type CreateAlbumRequest struct {
+	Title string `json:"title"`
+	// OrgID is the caller's organization, taken from the X-Org-ID
+	// header rather than the body.
+	OrgID string `json:"-"`
+	// CreatedAt optionally preserves an original creation timestamp,
+	// for data migrations. Absent means "now". The handler rejects
+	// values in the future beyond a small clock skew.
+	CreatedAt null.Time `json:"created_at"`
+}
+
+// CreateAlbumResponse is the body returned by POST /v1/album.
+type CreateAlbumResponse struct {
+	Album Album `json:"album"`
+}
+
+// PatchAlbumRequest is the JSON body accepted by PATCH /v1/album/{id}.
+// Pointer fields distinguish "absent" from "set to the zero value"; only
+// the fields present in the body are updated.
+type PatchAlbumRequest struct {
+	Title *string `json:"title"`
+}
+
+// Empty reports whether the patch carries no updatable fields.
+func (r PatchAlbumRequest) Empty() bool {
+	return r.Title == nil
+}
+
+// PatchAlbumRes is the body returned by PATCH /v1/album/{id}.
+type PatchAlbumRes struct {
+	Album Album `json:"album"`
+}
+
+// ListAlbumsReq captures the parsed query parameters of GET /v1/albums.
+type ListAlbumsReq struct {
+	Limit  int
+	Offset int
+	// StartDate and EndDate bound created_at when valid.
+	StartDate null.Time
+	EndDate   null.Time
+	// Query filters albums whose title contains the value,
+	// case-insensitively. Empty means no title filter.
+	Query string
+	// Cursor resumes a keyset-paginated listing. When set, Offset is
+	// ignored.
+	Cursor *Cursor
+	// Sort is one of the whitelisted sort values (created_at, title,
+	// updated_at, each optionally prefixed with - for descending),
+	// validated handler-side. Empty means created_at descending.
+	Sort string
+	// IncludeDeleted also returns soft-deleted albums. It is never set
+	// from API input; it exists for admin and maintenance callers.
+	IncludeDeleted bool
+	// OrgID scopes the listing to one organization. Empty matches only
+	// albums without an organization.
+	OrgID string
+}
+
+// CountAlbumsReq captures the parsed query parameters of
+// GET /v1/albums/count: the optional list filters, without pagination
+// or sorting.
+type CountAlbumsReq struct {
+	// StartDate and EndDate bound created_at when valid.
+	StartDate null.Time
+	EndDate   null.Time
+	// Query filters albums whose title contains the value,
+	// case-insensitively. Empty means no title filter.
+	Query string
+	// OrgID scopes the count to one organization. Empty matches only
+	// albums without an organization.
+	OrgID string
+}
+
+// CountAlbumsRes is the body returned by GET /v1/albums/count.
+type CountAlbumsRes struct {
+	Count int `json:"count"`
+}
+
+// ListAlbumsRes is the body returned by GET /v1/albums.
+type ListAlbumsRes struct {
+	Albums []Album `json:"albums"`
+	// NextCursor resumes the listing where this page left off. Absent
+	// on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// GetAlbumRes is the body returned by GET /v1/album/{id}.
+type GetAlbumRes struct {
+	Album Album `json:"album"`
+}
+
+// SetAlbumCoverRequest is the JSON body accepted by
+// PUT /v1/album/{id}/cover.
+type SetAlbumCoverRequest struct {
+	PhotoID string `json:"photo_id"`
+}
+
+// SetAlbumCoverRes is the body returned by PUT /v1/album/{id}/cover.
+type SetAlbumCoverRes struct {
+	Album Album `json:"album"`
+}
+
+// DeleteAlbumsRequest is the JSON body accepted by
+// POST /v1/albums:delete.
+type DeleteAlbumsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// DeleteAlbumsRes is the body returned by POST /v1/albums:delete.
+// Deleted counts the albums actually removed; ids that matched nothing
+// are silently skipped.
+type DeleteAlbumsRes struct {
+	Deleted int `json:"deleted"`
+}
@@ -0,0 +1,48 @@
+package catelog
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools/crypto"
+	"github.com/devchiran/golang-demo/tools/json"
+)
+
+// Cursor marks a position in the created_at-descending album listing
+// for keyset pagination. Listing resumes strictly after (CreatedAt, ID).
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// Encode seals the cursor into an opaque URL-safe token so clients can
+// neither inspect nor tamper with it.
+func (c Cursor) Encode(key []byte) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := crypto.Encrypt(key, b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecodeCursor reverses Encode. Tokens not produced with key, or
+// modified since, yield ErrInvalidCursor.
+func DecodeCursor(key []byte, token string) (Cursor, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	b, err := crypto.Decrypt(key, sealed)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
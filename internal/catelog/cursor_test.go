@@ -0,0 +1,59 @@
+package catelog
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cur := Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ID:        "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d",
+	}
+
+	token, err := cur.Encode(key)
+	if err != nil {
+		t.Fatalf("encoding cursor: %v", err)
+	}
+	got, err := DecodeCursor(key, token)
+	if err != nil {
+		t.Fatalf("decoding cursor: %v", err)
+	}
+	if !got.CreatedAt.Equal(cur.CreatedAt) || got.ID != cur.ID {
+		t.Errorf("round trip produced %+v, want %+v", got, cur)
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	token, err := Cursor{ID: "abc", CreatedAt: time.Now()}.Encode(key)
+	if err != nil {
+		t.Fatalf("encoding cursor: %v", err)
+	}
+
+	t.Run("flipped byte", func(t *testing.T) {
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			t.Fatalf("decoding token: %v", err)
+		}
+		raw[len(raw)-1] ^= 0xff
+		if _, err := DecodeCursor(key, base64.RawURLEncoding.EncodeToString(raw)); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other := []byte("fedcba9876543210fedcba9876543210")
+		if _, err := DecodeCursor(other, token); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("not base64", func(t *testing.T) {
+		if _, err := DecodeCursor(key, "%%%"); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
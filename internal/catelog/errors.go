@@ -0,0 +1,104 @@
+package catelog
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	// ErrNotFound is returned when the requested album does not exist.
+	ErrNotFound = errors.New("album not found")
+	// ErrGone is returned when the requested album existed but has been
+	// soft-deleted, so clients can tell a removal from a bad id.
+	ErrGone = errors.New("album has been deleted")
+	// ErrEmptyTitle is returned when an album title is missing.
+	ErrEmptyTitle = errors.New("album title must not be empty")
+	// ErrInvalidDate is returned when a date parameter cannot be
+	// parsed.
+	ErrInvalidDate = errors.New("invalid date")
+	// ErrInvalidCursor is returned when a pagination cursor cannot be
+	// decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidAlbumID is returned when an album id is not a UUID.
+	ErrInvalidAlbumID = errors.New("album id must be a valid UUID")
+	// ErrInvalidPhotoID is returned when a photo id is not a UUID.
+	ErrInvalidPhotoID = errors.New("photo id must be a valid UUID")
+	// ErrPhotoNotInAlbum is returned when a cover photo does not belong
+	// to the album it is being set on.
+	ErrPhotoNotInAlbum = errors.New("photo does not belong to the album")
+	// ErrTitleTooLong is returned when an album title exceeds
+	// MaxTitleLength.
+	ErrTitleTooLong = errors.New("album title must be at most 255 characters")
+)
+
+// MaxTitleLength is the longest album title the API accepts, matching
+// the CHECK constraint on albums.title.
+const MaxTitleLength = 255
+
+// ValidateTitle trims surrounding whitespace from a title and checks it
+// is neither empty nor too long, returning the cleaned title.
+func ValidateTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", ErrEmptyTitle
+	}
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", ErrTitleTooLong
+	}
+	return title, nil
+}
+
+// ValidateAlbumID checks that id has the UUID format album ids are
+// created with, so malformed ids are rejected before reaching the
+// database.
+func ValidateAlbumID(id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrInvalidAlbumID
+	}
+	return nil
+}
+
+// ValidatePhotoID checks that id has the UUID format photo ids are
+// created with.
+func ValidatePhotoID(id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrInvalidPhotoID
+	}
+	return nil
+}
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every invalid field in a request, so a
+// client learns about all of them in a single round-trip.
+type ValidationErrors []FieldError
+
+// Add records a failure for field.
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// Err returns v as an error, or nil when nothing was recorded.
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// Error summarizes which fields are invalid; the per-field messages
+// travel alongside in the response body.
+func (v ValidationErrors) Error() string {
+	fields := make([]string, len(v))
+	for i, fe := range v {
+		fields[i] = fe.Field
+	}
+	return "invalid fields: " + strings.Join(fields, ", ")
+}
@@ -0,0 +1,64 @@
+package catelog
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// Photo is a single photo within an album.
+type Photo struct {
+	ID          string      `db:"id" json:"id"`
+	AlbumID     string      `db:"album_id" json:"album_id"`
+	Filename    string      `db:"filename" json:"filename"`
+	ContentType null.String `db:"content_type" json:"content_type,omitempty"`
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+}
+
+// PresignPhotosRequest is the JSON body accepted by
+// POST /v1/album/{id}/photos:presign.
+type PresignPhotosRequest struct {
+	Photos []PresignPhoto `json:"photos"`
+}
+
+// PresignPhoto names one file the client wants to upload.
+type PresignPhoto struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+}
+
+// PresignPhotosRes is the body returned by
+// POST /v1/album/{id}/photos:presign. Each upload carries the photo id
+// the client must echo back when confirming.
+type PresignPhotosRes struct {
+	Uploads []PresignedUpload `json:"uploads"`
+}
+
+// PresignedUpload pairs a reserved photo id with the URL to upload the
+// file to.
+type PresignedUpload struct {
+	PhotoID  string `json:"photo_id"`
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+}
+
+// ConfirmPhotosRequest is the JSON body accepted by
+// POST /v1/album/{id}/photos:confirm, listing the uploads that
+// completed.
+type ConfirmPhotosRequest struct {
+	Photos []ConfirmPhoto `json:"photos"`
+}
+
+// ConfirmPhoto identifies one completed upload by the photo id issued
+// at presign time.
+type ConfirmPhoto struct {
+	PhotoID     string `json:"photo_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+}
+
+// ConfirmPhotosRes is the body returned by
+// POST /v1/album/{id}/photos:confirm.
+type ConfirmPhotosRes struct {
+	Photos []Photo `json:"photos"`
+}
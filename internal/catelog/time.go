@@ -0,0 +1,97 @@
+package catelog
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// TimeFormat selects the JSON wire format for Time values.
+type TimeFormat int
+
+const (
+	// TimeFormatRFC3339 serializes timestamps as RFC 3339 strings with
+	// nanosecond precision, Go's default. This is the package default.
+	TimeFormatRFC3339 TimeFormat = iota
+	// TimeFormatRFC3339Milli truncates the fractional seconds to
+	// milliseconds, for consumers that cannot represent nanoseconds.
+	TimeFormatRFC3339Milli
+	// TimeFormatUnixSeconds serializes timestamps as integer epoch
+	// seconds.
+	TimeFormatUnixSeconds
+)
+
+// rfc3339Milli is TimeFormatRFC3339Milli's layout; the zero-padded
+// fraction keeps the width stable across values.
+const rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+// timeFormat is the active wire format. Configure it once at startup
+// via SetTimeFormat; it is read on every marshal and is not guarded
+// against concurrent changes.
+var timeFormat = TimeFormatRFC3339
+
+// SetTimeFormat switches the JSON wire format used by every Time value,
+// for deployments whose consumers cannot handle the RFC 3339 default.
+func SetTimeFormat(f TimeFormat) {
+	timeFormat = f
+}
+
+// Time is a time.Time whose JSON representation follows the package's
+// configured TimeFormat, on output and input alike. It scans from and
+// serializes to the database exactly like time.Time.
+type Time struct {
+	time.Time
+}
+
+// NewTime wraps t for use in the JSON-facing timestamp fields.
+func NewTime(t time.Time) Time {
+	return Time{Time: t}
+}
+
+// MarshalJSON renders the timestamp in the configured format.
+func (t Time) MarshalJSON() ([]byte, error) {
+	switch timeFormat {
+	case TimeFormatRFC3339Milli:
+		return []byte(strconv.Quote(t.Format(rfc3339Milli))), nil
+	case TimeFormatUnixSeconds:
+		return []byte(strconv.FormatInt(t.Unix(), 10)), nil
+	default:
+		return t.Time.MarshalJSON()
+	}
+}
+
+// UnmarshalJSON parses the configured format. Both RFC 3339 variants
+// accept any fractional-second precision on input, so a format change
+// never breaks previously issued payloads.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	if timeFormat == TimeFormatUnixSeconds {
+		sec, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return ErrInvalidDate
+		}
+		t.Time = time.Unix(sec, 0).UTC()
+		return nil
+	}
+	return t.Time.UnmarshalJSON(b)
+}
+
+// Scan implements sql.Scanner so store queries can read directly into
+// the wrapper.
+func (t *Time) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	default:
+		return errors.New("catelog: cannot scan non-time value into Time")
+	}
+}
+
+// Value implements driver.Valuer, passing the bare time to the driver.
+func (t Time) Value() (driver.Value, error) {
+	return t.Time, nil
+}
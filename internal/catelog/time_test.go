@@ -0,0 +1,97 @@
+package catelog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// setTimeFormat switches the package format for one test and restores
+// the default afterwards, since the setting is process-wide.
+func setTimeFormat(t *testing.T, f TimeFormat) {
+	t.Helper()
+	SetTimeFormat(f)
+	t.Cleanup(func() { SetTimeFormat(TimeFormatRFC3339) })
+}
+
+func TestTimeRoundTripRFC3339(t *testing.T) {
+	in := NewTime(time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC))
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(b) != `"2024-05-01T12:30:45.123456789Z"` {
+		t.Errorf("marshaled = %s, want the RFC 3339 default", b)
+	}
+	var out Time
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !out.Equal(in.Time) {
+		t.Errorf("round trip = %v, want %v", out, in)
+	}
+}
+
+func TestTimeRoundTripRFC3339Milli(t *testing.T) {
+	setTimeFormat(t, TimeFormatRFC3339Milli)
+
+	in := NewTime(time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC))
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(b) != `"2024-05-01T12:30:45.123Z"` {
+		t.Errorf("marshaled = %s, want millisecond precision", b)
+	}
+	var out Time
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !out.Equal(in.Truncate(time.Millisecond)) {
+		t.Errorf("round trip = %v, want %v at millisecond precision", out, in)
+	}
+}
+
+func TestTimeRoundTripUnixSeconds(t *testing.T) {
+	setTimeFormat(t, TimeFormatUnixSeconds)
+
+	in := NewTime(time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC))
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(b) != "1714566645" {
+		t.Errorf("marshaled = %s, want epoch seconds", b)
+	}
+	var out Time
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !out.Equal(in.Time) {
+		t.Errorf("round trip = %v, want %v", out, in)
+	}
+
+	if err := json.Unmarshal([]byte(`"not-a-number"`), &out); err == nil {
+		t.Error("expected an error for a non-numeric epoch value")
+	}
+}
+
+func TestTimeScan(t *testing.T) {
+	var ts Time
+	now := time.Now()
+	if err := ts.Scan(now); err != nil {
+		t.Fatalf("scanning a time: %v", err)
+	}
+	if !ts.Equal(now) {
+		t.Errorf("scanned = %v, want %v", ts, now)
+	}
+	if err := ts.Scan(nil); err != nil {
+		t.Fatalf("scanning nil: %v", err)
+	}
+	if !ts.IsZero() {
+		t.Errorf("scanned nil = %v, want the zero time", ts)
+	}
+	if err := ts.Scan(42); err == nil {
+		t.Error("expected an error scanning a non-time value")
+	}
+}
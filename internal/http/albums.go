@@ -0,0 +1,463 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/guregu/null.v4"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	httputils "github.com/devchiran/golang-demo/tools/http"
+	"github.com/devchiran/golang-demo/tools/postgres"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	// createdAtSkew is how far into the future a client-supplied
+	// created_at may reach before it is rejected as invalid.
+	createdAtSkew = 5 * time.Minute
+
+	// maxJSONBody caps the JSON bodies this API accepts. It sits well
+	// below the LimitReaderMiddleware cap, which also has to cover
+	// uploads and future bulkier endpoints.
+	maxJSONBody = 256 << 10
+)
+
+// readJSONBody decodes the request body into v, flattening decode
+// errors into one client-friendly message but keeping the size limit
+// error distinct.
+func readJSONBody(r *http.Request, v interface{}) error {
+	if err := httputils.ReadJSONLimited(r.Body, v, maxJSONBody); err != nil {
+		if errors.Is(err, httputils.ErrBodyTooLarge) {
+			return httputils.ErrBodyTooLarge
+		}
+		return errors.New("request body must be valid JSON")
+	}
+	return nil
+}
+
+// orgHeader carries the caller's organization until a real auth layer
+// exists. Reads are scoped to it; creates require it.
+const orgHeader = "X-Org-ID"
+
+// orgID extracts the caller's organization from the request. Empty
+// means the caller has none and sees only unassigned albums.
+func orgID(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(orgHeader))
+}
+
+// Machine-readable error types surfaced in the JSON error envelope.
+const (
+	errTypeValidation = "validation_error"
+	errTypeNotFound   = "not_found"
+	errTypeConflict   = "conflict"
+	errTypeGone       = "gone"
+)
+
+// validSorts are the accepted values of the sort query parameter; a
+// leading - means descending.
+var validSorts = map[string]bool{
+	"created_at": true, "-created_at": true,
+	"title": true, "-title": true,
+	"updated_at": true, "-updated_at": true,
+}
+
+// writeValidationError renders err as a 400, with the per-field
+// breakdown when err carries one.
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var fields []httputils.JSONFieldError
+	if ve, ok := err.(cl.ValidationErrors); ok {
+		for _, fe := range ve {
+			fields = append(fields, httputils.JSONFieldError{Field: fe.Field, Message: fe.Message})
+		}
+	}
+	httputils.WriteJSONErrorFieldsCtx(r.Context(), w, r.URL.Query(), errTypeValidation, err.Error(), fields, http.StatusBadRequest)
+}
+
+// ListAlbums handles GET /v1/albums.
+func (h *Handler) ListAlbums(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parseListAlbumsRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	albums, err := h.AlbumStore.ListAlbums(r.Context(), req)
+	if err != nil {
+		h.Logger.Error("listing albums", "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	if wantsCSV(r) {
+		h.writeAlbumsCSV(w, albums)
+		return
+	}
+	res := cl.ListAlbumsRes{Albums: albums}
+	if len(h.CursorKey) > 0 && len(albums) == req.Limit {
+		last := albums[len(albums)-1]
+		token, err := (cl.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID}).Encode(h.CursorKey)
+		if err != nil {
+			h.Logger.Error("encoding cursor", "error", err.Error())
+			httputils.InternalServerErrorHandler(h.Logger)(w, r)
+			return
+		}
+		res.NextCursor = token
+	}
+	httputils.WriteJSON(w, r.URL.Query(), res, http.StatusOK)
+}
+
+func (h *Handler) parseListAlbumsRequest(r *http.Request) (cl.ListAlbumsReq, error) {
+	q := r.URL.Query()
+	req := cl.ListAlbumsReq{Limit: defaultListLimit, OrgID: orgID(r)}
+	var ve cl.ValidationErrors
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxListLimit {
+			ve.Add("limit", "must be a positive integer up to 500")
+		} else {
+			req.Limit = n
+		}
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			ve.Add("offset", "must be a non-negative integer")
+		} else {
+			req.Offset = n
+		}
+	}
+	if s := q.Get("start_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			ve.Add("start_date", cl.ErrInvalidDate.Error())
+		} else {
+			req.StartDate = null.TimeFrom(t)
+		}
+	}
+	if s := q.Get("end_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			ve.Add("end_date", cl.ErrInvalidDate.Error())
+		} else {
+			req.EndDate = null.TimeFrom(t)
+		}
+	}
+	req.Query = q.Get("q")
+	if s := q.Get("sort"); s != "" {
+		if !validSorts[s] {
+			ve.Add("sort", "must be one of created_at, -created_at, title, -title, updated_at, -updated_at")
+		} else {
+			req.Sort = s
+		}
+	}
+	if s := q.Get("cursor"); s != "" {
+		cur, err := cl.DecodeCursor(h.CursorKey, s)
+		if err != nil {
+			ve.Add("cursor", cl.ErrInvalidCursor.Error())
+		} else {
+			req.Cursor = &cur
+		}
+	}
+	// Cursors encode a position in the default order; resuming one
+	// under a different order would skip or repeat rows.
+	if req.Cursor != nil && req.Sort != "" && req.Sort != "-created_at" {
+		ve.Add("sort", "cursor pagination only supports the default sort")
+	}
+	return req, ve.Err()
+}
+
+// CountAlbums handles GET /v1/albums/count. It accepts the list
+// filters but returns only the matching total, so dashboards do not
+// have to page through rows to get a number.
+func (h *Handler) CountAlbums(w http.ResponseWriter, r *http.Request) {
+	req, err := parseCountAlbumsRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	count, err := h.AlbumStore.CountAlbums(r.Context(), req)
+	if err != nil {
+		h.Logger.Error("counting albums", "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.CountAlbumsRes{Count: count}, http.StatusOK)
+}
+
+func parseCountAlbumsRequest(r *http.Request) (cl.CountAlbumsReq, error) {
+	q := r.URL.Query()
+	req := cl.CountAlbumsReq{OrgID: orgID(r), Query: q.Get("q")}
+	var ve cl.ValidationErrors
+	if s := q.Get("start_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			ve.Add("start_date", cl.ErrInvalidDate.Error())
+		} else {
+			req.StartDate = null.TimeFrom(t)
+		}
+	}
+	if s := q.Get("end_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			ve.Add("end_date", cl.ErrInvalidDate.Error())
+		} else {
+			req.EndDate = null.TimeFrom(t)
+		}
+	}
+	return req, ve.Err()
+}
+
+// maxBulkDeleteIDs caps how many albums one bulk delete may remove.
+const maxBulkDeleteIDs = 100
+
+// DeleteAlbums handles POST /v1/albums:delete, removing every album in
+// the body's id list along with its photos. Ids that match nothing are
+// skipped; the response reports how many albums were actually deleted.
+func (h *Handler) DeleteAlbums(w http.ResponseWriter, r *http.Request) {
+	req, err := parseDeleteAlbumsRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	deleted, err := h.AlbumStore.DeleteAlbums(r.Context(), req.IDs)
+	if err != nil {
+		h.Logger.Error("deleting albums", "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.DeleteAlbumsRes{Deleted: deleted}, http.StatusOK)
+}
+
+func parseDeleteAlbumsRequest(r *http.Request) (cl.DeleteAlbumsRequest, error) {
+	var req cl.DeleteAlbumsRequest
+	if err := readJSONBody(r, &req); err != nil {
+		return req, err
+	}
+	var ve cl.ValidationErrors
+	if len(req.IDs) == 0 {
+		ve.Add("ids", "at least one album id is required")
+	}
+	if len(req.IDs) > maxBulkDeleteIDs {
+		ve.Add("ids", "at most "+strconv.Itoa(maxBulkDeleteIDs)+" ids per request")
+	}
+	for i, id := range req.IDs {
+		if err := cl.ValidateAlbumID(id); err != nil {
+			ve.Add("ids["+strconv.Itoa(i)+"]", err.Error())
+		}
+	}
+	return req, ve.Err()
+}
+
+// wantsCSV reports whether the client asked for CSV, either via the
+// Accept header or the format query parameter.
+func wantsCSV(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "csv" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// writeAlbumsCSV streams the listing as a CSV attachment for the data
+// export use case.
+func (h *Handler) writeAlbumsCSV(w http.ResponseWriter, albums []cl.Album) {
+	header := []string{"id", "title", "created_at", "updated_at"}
+	err := httputils.WriteCSV(w, "albums.csv", header, func(cw *csv.Writer) error {
+		for _, a := range albums {
+			record := []string{
+				a.ID,
+				a.Title,
+				a.CreatedAt.Format(time.RFC3339),
+				a.UpdatedAt.Format(time.RFC3339),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.Logger.Error("writing albums csv", "error", err.Error())
+	}
+}
+
+// GetAlbum handles GET /v1/album/{id}.
+func (h *Handler) GetAlbum(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGetAlbumRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	album, err := h.AlbumStore.GetAlbum(r.Context(), orgID(r), id)
+	if errors.Is(err, cl.ErrNotFound) {
+		httputils.WriteJSONErrorWithTypeCtx(r.Context(), w, r.URL.Query(), errTypeNotFound, cl.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, cl.ErrGone) {
+		httputils.WriteJSONErrorWithTypeCtx(r.Context(), w, r.URL.Query(), errTypeGone, cl.ErrGone.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("getting album", "id", id, "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	etag := httputils.WeakETag(album.ID, strconv.FormatInt(album.UpdatedAt.UnixNano(), 10))
+	w.Header().Set("ETag", etag)
+	if httputils.IfNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.GetAlbumRes{Album: album}, http.StatusOK)
+}
+
+func parseGetAlbumRequest(r *http.Request) (string, error) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		return "", errors.New("album id is required")
+	}
+	if err := cl.ValidateAlbumID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// CreateAlbum handles POST /v1/album.
+func (h *Handler) CreateAlbum(w http.ResponseWriter, r *http.Request) {
+	req, err := parseCreateAlbumRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	album, err := h.AlbumStore.CreateAlbum(r.Context(), req)
+	if errors.Is(err, postgres.ErrUniqueViolation) {
+		httputils.WriteJSONErrorWithTypeCtx(r.Context(), w, r.URL.Query(), errTypeConflict, "album already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("creating album", "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.CreateAlbumResponse{Album: album}, http.StatusCreated)
+}
+
+// PatchAlbum handles PATCH /v1/album/{id}. Only the fields present in
+// the body are updated.
+func (h *Handler) PatchAlbum(w http.ResponseWriter, r *http.Request) {
+	id, req, err := parsePatchAlbumRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	album, err := h.AlbumStore.PatchAlbum(r.Context(), id, req)
+	if errors.Is(err, cl.ErrNotFound) {
+		httputils.WriteJSONErrorWithTypeCtx(r.Context(), w, r.URL.Query(), errTypeNotFound, cl.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("patching album", "id", id, "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.PatchAlbumRes{Album: album}, http.StatusOK)
+}
+
+// SetAlbumCover handles PUT /v1/album/{id}/cover. The photo must
+// already belong to the album.
+func (h *Handler) SetAlbumCover(w http.ResponseWriter, r *http.Request) {
+	id, req, err := parseSetAlbumCoverRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	album, err := h.AlbumStore.SetAlbumCover(r.Context(), id, req.PhotoID)
+	if errors.Is(err, cl.ErrPhotoNotInAlbum) {
+		writeValidationError(w, r, cl.ErrPhotoNotInAlbum)
+		return
+	}
+	if errors.Is(err, cl.ErrNotFound) {
+		httputils.WriteJSONErrorWithTypeCtx(r.Context(), w, r.URL.Query(), errTypeNotFound, cl.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("setting album cover", "id", id, "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.SetAlbumCoverRes{Album: album}, http.StatusOK)
+}
+
+func parseSetAlbumCoverRequest(r *http.Request) (string, cl.SetAlbumCoverRequest, error) {
+	var req cl.SetAlbumCoverRequest
+	id, err := parseGetAlbumRequest(r)
+	if err != nil {
+		return "", req, err
+	}
+	if err := readJSONBody(r, &req); err != nil {
+		return "", req, err
+	}
+	var ve cl.ValidationErrors
+	if err := cl.ValidatePhotoID(req.PhotoID); err != nil {
+		ve.Add("photo_id", err.Error())
+	}
+	if err := ve.Err(); err != nil {
+		return "", req, err
+	}
+	return id, req, nil
+}
+
+func parsePatchAlbumRequest(r *http.Request) (string, cl.PatchAlbumRequest, error) {
+	var req cl.PatchAlbumRequest
+	id, err := parseGetAlbumRequest(r)
+	if err != nil {
+		return "", req, err
+	}
+	if err := readJSONBody(r, &req); err != nil {
+		return "", req, err
+	}
+	if req.Empty() {
+		return "", req, errors.New("at least one updatable field is required")
+	}
+	var ve cl.ValidationErrors
+	if req.Title != nil {
+		title, err := cl.ValidateTitle(*req.Title)
+		if err != nil {
+			ve.Add("title", err.Error())
+		} else {
+			*req.Title = title
+		}
+	}
+	if err := ve.Err(); err != nil {
+		return "", req, err
+	}
+	return id, req, nil
+}
+
+func parseCreateAlbumRequest(r *http.Request) (cl.CreateAlbumRequest, error) {
+	var req cl.CreateAlbumRequest
+	if err := readJSONBody(r, &req); err != nil {
+		return req, err
+	}
+	var ve cl.ValidationErrors
+	if req.OrgID = orgID(r); req.OrgID == "" {
+		ve.Add("org_id", "the "+orgHeader+" header is required")
+	}
+	title, err := cl.ValidateTitle(req.Title)
+	if err != nil {
+		ve.Add("title", err.Error())
+	} else {
+		req.Title = title
+	}
+	// A supplied created_at preserves original timestamps for migrated
+	// data; anything past a small clock skew into the future is bogus.
+	if req.CreatedAt.Valid && req.CreatedAt.Time.After(time.Now().Add(createdAtSkew)) {
+		ve.Add("created_at", cl.ErrInvalidDate.Error())
+	}
+	return req, ve.Err()
+}
@@ -0,0 +1,834 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/mock"
+)
+
+// The fake is held to the same contract as the real store, so its
+// method signatures cannot drift from what the handlers expect.
+var _ AlbumStore = (*mock.AlbumStore)(nil)
+
+func newTestHandler(store *mock.AlbumStore) http.Handler {
+	h := &Handler{
+		Logger:     &mock.Logger{},
+		AlbumStore: store,
+		Name:       "test",
+		Version:    "test",
+	}
+	return h.Handler()
+}
+
+func TestListAlbumsCSV(t *testing.T) {
+	albums := []cl.Album{
+		{
+			ID:        "id-1",
+			Title:     `Weird, "quoted" title`,
+			CreatedAt: cl.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+			UpdatedAt: cl.NewTime(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)),
+		},
+		{
+			ID:        "id-2",
+			Title:     "Plain",
+			CreatedAt: cl.NewTime(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)),
+			UpdatedAt: cl.NewTime(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)),
+		},
+	}
+	handler := newTestHandler(&mock.AlbumStore{
+		ListAlbumsFn: func(context.Context, cl.ListAlbumsReq) ([]cl.Album, error) {
+			return albums, nil
+		},
+	})
+
+	for name, req := range map[string]*http.Request{
+		"accept header": func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/v1/albums", nil)
+			r.Header.Set("Accept", "text/csv")
+			return r
+		}(),
+		"format param": httptest.NewRequest(http.MethodGet, "/v1/albums?format=csv", nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+				t.Errorf("content type = %q, want text/csv", ct)
+			}
+			if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+				t.Errorf("content disposition = %q, want an attachment", cd)
+			}
+
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("parsing csv: %v", err)
+			}
+			if len(records) != 3 {
+				t.Fatalf("got %d rows, want header + 2", len(records))
+			}
+			wantHeader := []string{"id", "title", "created_at", "updated_at"}
+			for i, col := range wantHeader {
+				if records[0][i] != col {
+					t.Errorf("header[%d] = %q, want %q", i, records[0][i], col)
+				}
+			}
+			if records[1][1] != albums[0].Title {
+				t.Errorf("title round-tripped as %q, want %q", records[1][1], albums[0].Title)
+			}
+			if records[1][2] != "2024-05-01T12:00:00Z" {
+				t.Errorf("created_at = %q, want RFC3339", records[1][2])
+			}
+		})
+	}
+}
+
+func TestListAlbumsSortParam(t *testing.T) {
+	var gotSort string
+	handler := newTestHandler(&mock.AlbumStore{
+		ListAlbumsFn: func(_ context.Context, req cl.ListAlbumsReq) ([]cl.Album, error) {
+			gotSort = req.Sort
+			return nil, nil
+		},
+	})
+
+	for _, sort := range []string{"created_at", "-created_at", "title", "-title", "updated_at", "-updated_at"} {
+		t.Run(sort, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/albums?sort="+sort, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rec.Code)
+			}
+			if gotSort != sort {
+				t.Errorf("store received sort %q, want %q", gotSort, sort)
+			}
+		})
+	}
+
+	t.Run("invalid value", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/albums?sort=bogus", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestListAlbumsReportsAllInvalidFields(t *testing.T) {
+	handler := newTestHandler(&mock.AlbumStore{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/albums?limit=abc&offset=-1&start_date=nope", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Fields  []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"fields"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.Error.Message == "" {
+		t.Error("expected a summary message")
+	}
+	got := map[string]bool{}
+	for _, fe := range body.Error.Fields {
+		got[fe.Field] = true
+		if fe.Message == "" {
+			t.Errorf("field %s has no message", fe.Field)
+		}
+	}
+	for _, field := range []string{"limit", "offset", "start_date"} {
+		if !got[field] {
+			t.Errorf("expected field %s to be reported, got %v", field, body.Error.Fields)
+		}
+	}
+	if len(body.Error.Fields) != 3 {
+		t.Errorf("got %d field errors, want 3", len(body.Error.Fields))
+	}
+}
+
+func TestPatchAlbum(t *testing.T) {
+	handler := newTestHandler(&mock.AlbumStore{
+		PatchAlbumFn: func(_ context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error) {
+			return cl.Album{ID: id, Title: *req.Title}, nil
+		},
+	})
+
+	t.Run("title-only patch succeeds", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", strings.NewReader(`{"title":"Renamed"}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var res cl.PatchAlbumRes
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if res.Album.Title != "Renamed" {
+			t.Errorf("title = %q, want Renamed", res.Album.Title)
+		}
+	})
+
+	t.Run("empty patch is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", strings.NewReader(`{}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestGetAlbumMalformedID(t *testing.T) {
+	storeCalled := false
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			storeCalled = true
+			return cl.Album{}, nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/album/not-a-uuid", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed id, got %d", rec.Code)
+	}
+	if storeCalled {
+		t.Error("expected the store not to be called for a malformed id")
+	}
+}
+
+func TestGetAlbumWrappedNotFound(t *testing.T) {
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return cl.Album{}, errors.Wrap(cl.ErrNotFound, "getting album")
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a wrapped ErrNotFound, got %d", rec.Code)
+	}
+}
+
+func TestGetAlbumETag(t *testing.T) {
+	album := cl.Album{
+		ID:        "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d",
+		Title:     "Summer Holiday",
+		CreatedAt: cl.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		UpdatedAt: cl.NewTime(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)),
+	}
+	store := &mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return album, nil
+		},
+	}
+	handler := newTestHandler(store)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/album/"+album.ID, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+	var res cl.GetAlbumRes
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if res.Album.ID != album.ID {
+		t.Errorf("expected album %s, got %s", album.ID, res.Album.ID)
+	}
+
+	t.Run("if-none-match yields 304", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/album/"+album.ID, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotModified {
+			t.Fatalf("expected 304, got %d", rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("expected empty body, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("mismatched if-none-match yields 200", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/album/"+album.ID, nil)
+		req.Header.Set("If-None-Match", `W/"different"`)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+	})
+}
+
+func TestSetAlbumCover(t *testing.T) {
+	const (
+		albumID = "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"
+		photoID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	)
+	var gotAlbum, gotPhoto string
+	store := &mock.AlbumStore{
+		SetAlbumCoverFn: func(_ context.Context, albumID, photoID string) (cl.Album, error) {
+			gotAlbum, gotPhoto = albumID, photoID
+			return cl.Album{ID: albumID, Title: "Covered", CoverPhotoID: null.StringFrom(photoID)}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	body := strings.NewReader(`{"photo_id": "` + photoID + `"}`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/album/"+albumID+"/cover", body))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if gotAlbum != albumID || gotPhoto != photoID {
+		t.Errorf("store called with %s/%s, want %s/%s", gotAlbum, gotPhoto, albumID, photoID)
+	}
+	var res cl.SetAlbumCoverRes
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !res.Album.CoverPhotoID.Valid || res.Album.CoverPhotoID.String != photoID {
+		t.Errorf("cover_photo_id = %v, want %s", res.Album.CoverPhotoID, photoID)
+	}
+}
+
+func TestSetAlbumCoverPhotoMismatch(t *testing.T) {
+	store := &mock.AlbumStore{
+		SetAlbumCoverFn: func(context.Context, string, string) (cl.Album, error) {
+			return cl.Album{}, cl.ErrPhotoNotInAlbum
+		},
+	}
+	h := newTestHandler(store)
+
+	body := strings.NewReader(`{"photo_id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d/cover", body))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), cl.ErrPhotoNotInAlbum.Error()) {
+		t.Errorf("body %s does not mention the mismatch", rec.Body.String())
+	}
+}
+
+func TestSetAlbumCoverInvalidPhotoID(t *testing.T) {
+	store := &mock.AlbumStore{}
+	h := newTestHandler(store)
+
+	body := strings.NewReader(`{"photo_id": "not-a-uuid"}`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d/cover", body))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateAlbumFutureCreatedAtRejected(t *testing.T) {
+	called := false
+	store := &mock.AlbumStore{
+		CreateAlbumFn: func(context.Context, cl.CreateAlbumRequest) (cl.Album, error) {
+			called = true
+			return cl.Album{}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	body := strings.NewReader(`{"title": "Migrated", "created_at": "` + future + `"}`)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/album", body)
+	req.Header.Set("X-Org-ID", "org-test")
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a future created_at, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "created_at") {
+		t.Errorf("body %s does not name the created_at field", rec.Body.String())
+	}
+	if called {
+		t.Error("store must not be called for an invalid created_at")
+	}
+}
+
+func TestCreateAlbumTitleValidation(t *testing.T) {
+	var gotTitle string
+	called := false
+	handler := newTestHandler(&mock.AlbumStore{
+		CreateAlbumFn: func(_ context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+			called, gotTitle = true, req.Title
+			return cl.Album{ID: "id-1", Title: req.Title}, nil
+		},
+	})
+
+	post := func(t *testing.T, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		called, gotTitle = false, ""
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/album", strings.NewReader(body))
+		req.Header.Set("X-Org-ID", "org-test")
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("whitespace-only title rejected", func(t *testing.T) {
+		rec := post(t, `{"title":"   \t "}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), cl.ErrEmptyTitle.Error()) {
+			t.Errorf("body = %s, want it to mention the empty title", rec.Body.String())
+		}
+		if called {
+			t.Error("store should not be called")
+		}
+	})
+
+	t.Run("too-long title rejected", func(t *testing.T) {
+		rec := post(t, `{"title":"`+strings.Repeat("x", cl.MaxTitleLength+1)+`"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), cl.ErrTitleTooLong.Error()) {
+			t.Errorf("body = %s, want it to mention the length limit", rec.Body.String())
+		}
+		if called {
+			t.Error("store should not be called")
+		}
+	})
+
+	t.Run("surrounding whitespace trimmed", func(t *testing.T) {
+		rec := post(t, `{"title":"  Summer 2024  "}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if gotTitle != "Summer 2024" {
+			t.Errorf("store received title %q, want it trimmed", gotTitle)
+		}
+	})
+
+	t.Run("title at the limit accepted", func(t *testing.T) {
+		title := strings.Repeat("x", cl.MaxTitleLength)
+		rec := post(t, `{"title":"`+title+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if gotTitle != title {
+			t.Errorf("store received a %d-rune title, want %d", len(gotTitle), cl.MaxTitleLength)
+		}
+	})
+}
+
+func TestPatchAlbumTitleValidation(t *testing.T) {
+	var gotTitle string
+	handler := newTestHandler(&mock.AlbumStore{
+		PatchAlbumFn: func(_ context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error) {
+			gotTitle = *req.Title
+			return cl.Album{ID: id, Title: *req.Title}, nil
+		},
+	})
+	const id = "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/album/"+id, strings.NewReader(`{"title":" \n "}`))
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a whitespace-only title, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPatch, "/v1/album/"+id, strings.NewReader(`{"title":" Trimmed "}`))
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if gotTitle != "Trimmed" {
+		t.Errorf("store received title %q, want it trimmed", gotTitle)
+	}
+}
+
+func TestCreateAlbumRequiresOrgHeader(t *testing.T) {
+	storeCalled := false
+	handler := newTestHandler(&mock.AlbumStore{
+		CreateAlbumFn: func(_ context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+			storeCalled = true
+			return cl.Album{ID: "id", Title: req.Title}, nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/album", strings.NewReader(`{"title":"No Org"}`)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without the org header, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "org_id") {
+		t.Errorf("body %q does not name the org_id field", rec.Body.String())
+	}
+	if storeCalled {
+		t.Error("expected the store not to be called without an organization")
+	}
+}
+
+func TestAlbumOrgHeaderForwarded(t *testing.T) {
+	var createReq cl.CreateAlbumRequest
+	var listReq cl.ListAlbumsReq
+	var getOrgID string
+	handler := newTestHandler(&mock.AlbumStore{
+		CreateAlbumFn: func(_ context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+			createReq = req
+			return cl.Album{ID: "id", Title: req.Title}, nil
+		},
+		ListAlbumsFn: func(_ context.Context, req cl.ListAlbumsReq) ([]cl.Album, error) {
+			listReq = req
+			return nil, nil
+		},
+		GetAlbumFn: func(_ context.Context, orgID, id string) (cl.Album, error) {
+			getOrgID = orgID
+			return cl.Album{ID: id}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/album", strings.NewReader(`{"title":"Scoped"}`))
+	req.Header.Set("X-Org-ID", "org-a")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if createReq.OrgID != "org-a" {
+		t.Errorf("create request org id = %q, want org-a", createReq.OrgID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/albums", nil)
+	req.Header.Set("X-Org-ID", "org-a")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if listReq.OrgID != "org-a" {
+		t.Errorf("list request org id = %q, want org-a", listReq.OrgID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", nil)
+	req.Header.Set("X-Org-ID", "org-a")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if getOrgID != "org-a" {
+		t.Errorf("get album org id = %q, want org-a", getOrgID)
+	}
+}
+
+func TestCountAlbums(t *testing.T) {
+	var gotReq cl.CountAlbumsReq
+	handler := newTestHandler(&mock.AlbumStore{
+		CountAlbumsFn: func(_ context.Context, req cl.CountAlbumsReq) (int, error) {
+			gotReq = req
+			return 42, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/albums/count?q=holiday&start_date=2024-01-01T00:00:00Z", nil)
+	req.Header.Set("X-Org-ID", "org-a")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res cl.CountAlbumsRes
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if res.Count != 42 {
+		t.Errorf("count = %d, want 42", res.Count)
+	}
+	if gotReq.Query != "holiday" || gotReq.OrgID != "org-a" {
+		t.Errorf("store received %+v, want the q and org filters forwarded", gotReq)
+	}
+	if !gotReq.StartDate.Valid || !gotReq.StartDate.Time.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("store received start date %+v, want 2024-01-01", gotReq.StartDate)
+	}
+}
+
+func TestCountAlbumsInvalidDate(t *testing.T) {
+	storeCalled := false
+	handler := newTestHandler(&mock.AlbumStore{
+		CountAlbumsFn: func(context.Context, cl.CountAlbumsReq) (int, error) {
+			storeCalled = true
+			return 0, nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/albums/count?end_date=yesterday", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed date, got %d", rec.Code)
+	}
+	if storeCalled {
+		t.Error("expected the store not to be called for a malformed date")
+	}
+}
+
+// TestAlbumResponseShapes pins the documented response convention:
+// resource-named envelopes, never a generic data wrapper.
+func TestAlbumResponseShapes(t *testing.T) {
+	album := cl.Album{ID: "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", Title: "Shape"}
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(_ context.Context, _, id string) (cl.Album, error) {
+			return album, nil
+		},
+		ListAlbumsFn: func(context.Context, cl.ListAlbumsReq) ([]cl.Album, error) {
+			return []cl.Album{album}, nil
+		},
+		CreateAlbumFn: func(_ context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+			return album, nil
+		},
+	})
+
+	for name, tc := range map[string]struct {
+		req  *http.Request
+		want string
+	}{
+		"get":  {httptest.NewRequest(http.MethodGet, "/v1/album/"+album.ID, nil), "album"},
+		"list": {httptest.NewRequest(http.MethodGet, "/v1/albums", nil), "albums"},
+		"create": {func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/v1/album", strings.NewReader(`{"title":"Shape"}`))
+			req.Header.Set("X-Org-ID", "org-test")
+			return req
+		}(), "album"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tc.req)
+			if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+				t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if _, ok := body[tc.want]; !ok {
+				t.Errorf("body %s has no top-level %q key", rec.Body.String(), tc.want)
+			}
+			if _, ok := body["data"]; ok {
+				t.Errorf("body %s wraps the result in data; album responses are resource-named", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestErrorBodyEchoesRequestID(t *testing.T) {
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return cl.Album{}, cl.ErrNotFound
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", nil)
+	req.Header.Set("X-Request-Id", "req-debug-1")
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.RequestID != "req-debug-1" {
+		t.Errorf("request_id = %q, want the caller-supplied id echoed", body.RequestID)
+	}
+}
+
+func TestDeleteAlbums(t *testing.T) {
+	var gotIDs []string
+	handler := newTestHandler(&mock.AlbumStore{
+		DeleteAlbumsFn: func(_ context.Context, ids []string) (int, error) {
+			gotIDs = ids
+			return 2, nil
+		},
+	})
+
+	body := `{"ids": ["9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", "1b671a64-40d5-491e-99b0-da01ff1f3341", "00000000-0000-0000-0000-000000000000"]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/albums:delete", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(gotIDs) != 3 {
+		t.Errorf("store saw %d ids, want all 3 forwarded", len(gotIDs))
+	}
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if res.Deleted != 2 {
+		t.Errorf("deleted = %d, want the store's count", res.Deleted)
+	}
+}
+
+func TestDeleteAlbumsValidation(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty list":   `{"ids": []}`,
+		"missing ids":  `{}`,
+		"malformed id": `{"ids": ["not-a-uuid"]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			storeCalled := false
+			handler := newTestHandler(&mock.AlbumStore{
+				DeleteAlbumsFn: func(context.Context, []string) (int, error) {
+					storeCalled = true
+					return 0, nil
+				},
+			})
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/albums:delete", strings.NewReader(body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), "ids") {
+				t.Errorf("expected the error to name the ids field, got %s", rec.Body.String())
+			}
+			if storeCalled {
+				t.Error("expected the store not to be called")
+			}
+		})
+	}
+}
+
+func TestHeadAlbumRoutes(t *testing.T) {
+	album := cl.Album{
+		ID:        "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d",
+		Title:     "Head Request",
+		CreatedAt: cl.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		UpdatedAt: cl.NewTime(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)),
+	}
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return album, nil
+		},
+		ListAlbumsFn: func(context.Context, cl.ListAlbumsReq) ([]cl.Album, error) {
+			return []cl.Album{album}, nil
+		},
+		CountAlbumsFn: func(context.Context, cl.CountAlbumsReq) (int, error) {
+			return 1, nil
+		},
+	})
+	// A real server is needed here: net/http discards HEAD bodies at the
+	// transport layer, which a bare recorder would not exercise.
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	do := func(method, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatalf("building request: %v", err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	for _, path := range []string{"/v1/album/" + album.ID, "/v1/albums", "/v1/albums/count"} {
+		get := do(http.MethodGet, path)
+		head := do(http.MethodHead, path)
+
+		if head.StatusCode != get.StatusCode {
+			t.Errorf("HEAD %s = %d, want GET's %d", path, head.StatusCode, get.StatusCode)
+		}
+		if g, h := get.Header.Get("ETag"), head.Header.Get("ETag"); h != g {
+			t.Errorf("HEAD %s ETag = %q, want GET's %q", path, h, g)
+		}
+		body, err := io.ReadAll(head.Body)
+		if err != nil {
+			t.Fatalf("reading HEAD body: %v", err)
+		}
+		if len(body) != 0 {
+			t.Errorf("HEAD %s returned %d body bytes, want none", path, len(body))
+		}
+	}
+
+	etag := do(http.MethodGet, "/v1/album/"+album.ID).Header.Get("ETag")
+	req, err := http.NewRequest(http.MethodHead, srv.URL+"/v1/album/"+album.ID, nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	req.Header.Set("If-None-Match", etag)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("conditional HEAD: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional HEAD = %d, want 304", res.StatusCode)
+	}
+}
+
+func TestGetAlbumGone(t *testing.T) {
+	handler := newTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return cl.Album{}, cl.ErrGone
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", nil))
+
+	if rec.Code != http.StatusGone {
+		t.Fatalf("expected 410 for a soft-deleted album, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"type":"gone"`) {
+		t.Errorf("expected the gone error type, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	httputils "github.com/devchiran/golang-demo/tools/http"
+)
+
+// routeInfo describes one registered route in the /debug/routes listing.
+type routeInfo struct {
+	Name    string   `json:"name,omitempty"`
+	Path    string   `json:"path"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// ListRoutes reports every route registered on the router, for
+// operators checking what a running instance actually serves. It is
+// only mounted when DebugRoutes is set.
+func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	var routes []routeInfo
+	err := h.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil // subrouter prefixes have no template
+		}
+		methods, _ := route.GetMethods() //nolint:errcheck // no methods is fine
+		routes = append(routes, routeInfo{
+			Name:    route.GetName(),
+			Path:    path,
+			Methods: methods,
+		})
+		return nil
+	})
+	if err != nil {
+		h.Logger.Error("walking routes", "error", err.Error())
+		httputils.WriteJSONError(w, r.URL.Query(), "listing routes", http.StatusInternalServerError)
+		return
+	}
+	httputils.WriteJSONData(w, r.URL.Query(), map[string]interface{}{"routes": routes}, http.StatusOK)
+}
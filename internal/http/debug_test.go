@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func TestListRoutes(t *testing.T) {
+	h := &Handler{
+		Logger:      &mock.Logger{},
+		AlbumStore:  &mock.AlbumStore{},
+		Name:        "test",
+		Version:     "test",
+		DebugRoutes: true,
+	}
+	handler := h.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Data struct {
+			Routes []routeInfo `json:"routes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	byName := map[string]routeInfo{}
+	for _, rt := range body.Data.Routes {
+		byName[rt.Name] = rt
+	}
+	for name, want := range map[string]struct {
+		path    string
+		methods []string
+	}{
+		"list_albums":  {"/v1/albums", []string{http.MethodGet, http.MethodHead}},
+		"get_album":    {"/v1/album/{id}", []string{http.MethodGet, http.MethodHead}},
+		"create_album": {"/v1/album", []string{http.MethodPost}},
+	} {
+		rt, ok := byName[name]
+		if !ok {
+			t.Errorf("route %s missing from listing %v", name, byName)
+			continue
+		}
+		if rt.Path != want.path {
+			t.Errorf("%s path = %s, want %s", name, rt.Path, want.path)
+		}
+		if !reflect.DeepEqual(rt.Methods, want.methods) {
+			t.Errorf("%s methods = %v, want %v", name, rt.Methods, want.methods)
+		}
+	}
+}
+
+func TestListRoutesDisabledByDefault(t *testing.T) {
+	h := newTestHandler(&mock.AlbumStore{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when the flag is off, got %d", rec.Code)
+	}
+}
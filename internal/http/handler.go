@@ -0,0 +1,70 @@
+// Package http exposes the album catalog over a JSON HTTP API.
+//
+// Successful album responses use resource-named envelopes — {"album":
+// ...}, {"albums": ...}, {"count": ...} — matching the schemas in
+// openapi.json, not the generic {"data": ...} wrapper WriteJSONData
+// offers. Errors always use the {"error": ...} envelope. The version
+// endpoint predates this convention and keeps its data wrapper.
+package http
+
+import (
+	"context"
+
+	"github.com/gorilla/mux"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// AlbumStore is the canonical persistence surface the handlers depend
+// on. *postgres.Postgres and *mock.AlbumStore both implement it, held
+// to the contract by compile-time assertions, so the real store and
+// the test fake cannot drift apart.
+type AlbumStore interface {
+	ListAlbums(ctx context.Context, req cl.ListAlbumsReq) ([]cl.Album, error)
+	CountAlbums(ctx context.Context, req cl.CountAlbumsReq) (int, error)
+	GetAlbum(ctx context.Context, orgID, id string) (cl.Album, error)
+	CreateAlbum(ctx context.Context, req cl.CreateAlbumRequest) (cl.Album, error)
+	PatchAlbum(ctx context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error)
+	SetAlbumCover(ctx context.Context, albumID, photoID string) (cl.Album, error)
+	DeleteAlbums(ctx context.Context, ids []string) (int, error)
+	SubscribeAlbumChanges(ctx context.Context) (<-chan string, error)
+	CreatePhotos(ctx context.Context, albumID string, photos []cl.Photo) ([]cl.Photo, error)
+}
+
+// PhotoStorage presigns direct-to-storage uploads, in the style of S3
+// or GCS signed URLs. The object key is chosen by the handler; the
+// implementation only signs it.
+type PhotoStorage interface {
+	Presign(ctx context.Context, object, contentType string) (url string, err error)
+}
+
+// Handler serves the album API.
+type Handler struct {
+	Logger     tools.Logger
+	Stats      tools.StatsClient
+	AlbumStore AlbumStore
+	// PhotoStorage signs upload URLs for the bulk photo upload flow.
+	// When nil, the presign endpoint responds 501.
+	PhotoStorage PhotoStorage
+	Name         string
+	Version      string
+	// CursorKey seals pagination cursors. When empty, listings never
+	// include a next_cursor and cursor parameters are rejected.
+	CursorKey []byte
+	// DebugRoutes exposes GET /debug/routes, a listing of every
+	// registered route. Leave it off in production.
+	DebugRoutes bool
+	// Metrics mounts the StatsClient's scrape handler at GET /metrics.
+	// It only takes effect when Stats is set and exposes a handler.
+	Metrics bool
+	// DebugPprof exposes the standard runtime profiler under
+	// /debug/pprof. Like DebugRoutes, leave it off in production.
+	DebugPprof bool
+	// MaxConnections and ConcurrentLimit tune the admission middleware.
+	// Non-positive values fall back to 5000 and 250 respectively.
+	MaxConnections  int64
+	ConcurrentLimit int64
+
+	router *mux.Router
+}
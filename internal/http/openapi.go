@@ -0,0 +1,30 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+
+	httputils "github.com/devchiran/golang-demo/tools/http"
+)
+
+// openAPIDoc is the hand-maintained OpenAPI 3 description of the
+// service. It is embedded so the binary is self-describing; keep it in
+// step with the routes in router.go — the test compares the two.
+//
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// OpenAPI serves the embedded API description. The document only
+// changes with a deploy, so the ETag is computed once and pollers get
+// 304s in between.
+func (h *Handler) OpenAPI() http.HandlerFunc {
+	etag := httputils.WeakETag(string(openAPIDoc))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if httputils.IfNoneMatch(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		httputils.WriteRawJSON(w, openAPIDoc, http.StatusOK)
+	}
+}
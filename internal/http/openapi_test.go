@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	h := newTestHandler(&mock.AlbumStore{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("content type = %q", got)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Error("expected an ETag header")
+	}
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	if doc.OpenAPI == "" {
+		t.Error("document has no openapi version field")
+	}
+
+	// Every registered route must be documented and vice versa, so the
+	// document cannot silently drift from router.go.
+	registered := map[string]bool{}
+	handler := &Handler{Logger: &mock.Logger{}, AlbumStore: &mock.AlbumStore{}, Name: "test", Version: "test"}
+	handler.Handler()
+	err := handler.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil // subrouter prefixes have no template of their own
+		}
+		registered[tpl] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking router: %v", err)
+	}
+
+	for path := range doc.Paths {
+		if !registered[path] {
+			t.Errorf("documented path %s is not registered on the router", path)
+		}
+	}
+	for tpl := range registered {
+		if tpl == "/v1" {
+			continue // the subrouter prefix, not a route
+		}
+		if _, ok := doc.Paths[tpl]; !ok {
+			t.Errorf("registered route %s is missing from the document", tpl)
+		}
+	}
+}
+
+func TestOpenAPINotModified(t *testing.T) {
+	h := newTestHandler(&mock.AlbumStore{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 with a matching If-None-Match, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected an empty body on 304, got %d bytes", rec.Body.Len())
+	}
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// requireExactlyOne reports which single one of keys carries a value in
+// v, treating empty values as absent like the rest of the query
+// parsing does. It returns an error naming the candidates when none is
+// provided, and the conflicting keys when more than one is, so
+// handlers with mutually-exclusive parameters (id vs title, cursor vs
+// offset) can surface the same 400 wording.
+func requireExactlyOne(v url.Values, keys ...string) (string, error) {
+	var provided []string
+	for _, key := range keys {
+		if v.Get(key) != "" {
+			provided = append(provided, key)
+		}
+	}
+	switch len(provided) {
+	case 0:
+		return "", errors.Errorf("exactly one of %s must be provided", strings.Join(keys, ", "))
+	case 1:
+		return provided[0], nil
+	default:
+		return "", errors.Errorf("only one of %s may be provided, got %s",
+			strings.Join(keys, ", "), strings.Join(provided, " and "))
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRequireExactlyOne(t *testing.T) {
+	keys := []string{"id", "title"}
+
+	t.Run("one provided", func(t *testing.T) {
+		v := url.Values{"title": {"Holiday"}}
+		key, err := requireExactlyOne(v, keys...)
+		if err != nil {
+			t.Fatalf("requireExactlyOne: %v", err)
+		}
+		if key != "title" {
+			t.Errorf("key = %q, want title", key)
+		}
+	})
+
+	t.Run("none provided", func(t *testing.T) {
+		// An empty value counts as absent, matching how the handlers
+		// read optional parameters.
+		v := url.Values{"id": {""}}
+		if _, err := requireExactlyOne(v, keys...); err == nil {
+			t.Fatal("expected an error when no key is provided")
+		} else if !strings.Contains(err.Error(), "id, title") {
+			t.Errorf("error %q does not name the candidate keys", err)
+		}
+	})
+
+	t.Run("multiple provided", func(t *testing.T) {
+		v := url.Values{"id": {"123"}, "title": {"Holiday"}}
+		_, err := requireExactlyOne(v, keys...)
+		if err == nil {
+			t.Fatal("expected an error when both keys are provided")
+		}
+		if !strings.Contains(err.Error(), "id and title") {
+			t.Errorf("error %q does not name the conflicting keys", err)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	httputils "github.com/devchiran/golang-demo/tools/http"
+)
+
+// maxUploadBatch caps how many photos one presign or confirm request
+// may carry.
+const maxUploadBatch = 100
+
+// PresignPhotoUploads handles POST /v1/album/{id}/photos:presign. It
+// reserves a photo id per file and returns a presigned upload URL for
+// each; nothing is persisted until the client confirms.
+func (h *Handler) PresignPhotoUploads(w http.ResponseWriter, r *http.Request) {
+	if h.PhotoStorage == nil {
+		httputils.WriteJSONError(w, r.URL.Query(), "photo storage is not configured", http.StatusNotImplemented)
+		return
+	}
+	id, req, err := parsePresignPhotosRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	// Resolve the album up front so a typo'd id fails here, not after
+	// the client has uploaded everything.
+	if _, err := h.AlbumStore.GetAlbum(r.Context(), orgID(r), id); err != nil {
+		if errors.Is(err, cl.ErrNotFound) {
+			httputils.WriteJSONErrorWithType(w, r.URL.Query(), errTypeNotFound, cl.ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, cl.ErrGone) {
+			httputils.WriteJSONErrorWithType(w, r.URL.Query(), errTypeGone, cl.ErrGone.Error(), http.StatusGone)
+			return
+		}
+		h.Logger.Error("getting album for presign", "id", id, "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	res := cl.PresignPhotosRes{Uploads: make([]cl.PresignedUpload, 0, len(req.Photos))}
+	for _, photo := range req.Photos {
+		photoID, err := uuid.NewV4()
+		if err != nil {
+			h.Logger.Error("generating photo id", "error", err.Error())
+			httputils.InternalServerErrorHandler(h.Logger)(w, r)
+			return
+		}
+		object := fmt.Sprintf("%s/%s/%s", id, photoID, photo.Filename)
+		url, err := h.PhotoStorage.Presign(r.Context(), object, photo.ContentType)
+		if err != nil {
+			h.Logger.Error("presigning upload", "album_id", id, "error", err.Error())
+			httputils.InternalServerErrorHandler(h.Logger)(w, r)
+			return
+		}
+		res.Uploads = append(res.Uploads, cl.PresignedUpload{
+			PhotoID:  photoID.String(),
+			Filename: photo.Filename,
+			URL:      url,
+		})
+	}
+	httputils.WriteJSON(w, r.URL.Query(), res, http.StatusOK)
+}
+
+func parsePresignPhotosRequest(r *http.Request) (string, cl.PresignPhotosRequest, error) {
+	var req cl.PresignPhotosRequest
+	id, err := parseGetAlbumRequest(r)
+	if err != nil {
+		return "", req, err
+	}
+	if err := readJSONBody(r, &req); err != nil {
+		return "", req, err
+	}
+	var ve cl.ValidationErrors
+	if len(req.Photos) == 0 {
+		ve.Add("photos", "at least one photo is required")
+	}
+	if len(req.Photos) > maxUploadBatch {
+		ve.Add("photos", "at most "+strconv.Itoa(maxUploadBatch)+" photos per request")
+	}
+	for i, photo := range req.Photos {
+		if photo.Filename == "" {
+			ve.Add("photos["+strconv.Itoa(i)+"].filename", "filename is required")
+		}
+	}
+	if err := ve.Err(); err != nil {
+		return "", req, err
+	}
+	return id, req, nil
+}
+
+// ConfirmPhotoUploads handles POST /v1/album/{id}/photos:confirm,
+// recording a Photo row for every upload the client completed.
+func (h *Handler) ConfirmPhotoUploads(w http.ResponseWriter, r *http.Request) {
+	id, req, err := parseConfirmPhotosRequest(r)
+	if err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+	photos := make([]cl.Photo, 0, len(req.Photos))
+	for _, photo := range req.Photos {
+		p := cl.Photo{ID: photo.PhotoID, AlbumID: id, Filename: photo.Filename}
+		if photo.ContentType != "" {
+			p.ContentType.SetValid(photo.ContentType)
+		}
+		photos = append(photos, p)
+	}
+	created, err := h.AlbumStore.CreatePhotos(r.Context(), id, photos)
+	if errors.Is(err, cl.ErrNotFound) {
+		httputils.WriteJSONErrorWithType(w, r.URL.Query(), errTypeNotFound, cl.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("confirming photo uploads", "album_id", id, "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	httputils.WriteJSON(w, r.URL.Query(), cl.ConfirmPhotosRes{Photos: created}, http.StatusCreated)
+}
+
+func parseConfirmPhotosRequest(r *http.Request) (string, cl.ConfirmPhotosRequest, error) {
+	var req cl.ConfirmPhotosRequest
+	id, err := parseGetAlbumRequest(r)
+	if err != nil {
+		return "", req, err
+	}
+	if err := readJSONBody(r, &req); err != nil {
+		return "", req, err
+	}
+	var ve cl.ValidationErrors
+	if len(req.Photos) == 0 {
+		ve.Add("photos", "at least one photo is required")
+	}
+	if len(req.Photos) > maxUploadBatch {
+		ve.Add("photos", "at most "+strconv.Itoa(maxUploadBatch)+" photos per request")
+	}
+	for i, photo := range req.Photos {
+		if err := cl.ValidatePhotoID(photo.PhotoID); err != nil {
+			ve.Add("photos["+strconv.Itoa(i)+"].photo_id", err.Error())
+		}
+		if photo.Filename == "" {
+			ve.Add("photos["+strconv.Itoa(i)+"].filename", "filename is required")
+		}
+	}
+	if err := ve.Err(); err != nil {
+		return "", req, err
+	}
+	return id, req, nil
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/mock"
+)
+
+const testAlbumID = "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"
+
+func newPhotoTestHandler(store *mock.AlbumStore, storage PhotoStorage) http.Handler {
+	h := &Handler{
+		Logger:       &mock.Logger{},
+		AlbumStore:   store,
+		PhotoStorage: storage,
+		Name:         "test",
+		Version:      "test",
+	}
+	return h.Handler()
+}
+
+func TestPresignPhotoUploads(t *testing.T) {
+	var objects []string
+	storage := &mock.PhotoStorage{
+		PresignFn: func(_ context.Context, object, contentType string) (string, error) {
+			objects = append(objects, object)
+			return "https://uploads.example.com/" + object, nil
+		},
+	}
+	handler := newPhotoTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(_ context.Context, _, id string) (cl.Album, error) {
+			return cl.Album{ID: id}, nil
+		},
+	}, storage)
+
+	body := `{"photos":[{"filename":"a.jpg","content_type":"image/jpeg"},{"filename":"b.png","content_type":"image/png"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:presign", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res cl.PresignPhotosRes
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if len(res.Uploads) != 2 {
+		t.Fatalf("got %d uploads, want 2", len(res.Uploads))
+	}
+	for i, filename := range []string{"a.jpg", "b.png"} {
+		up := res.Uploads[i]
+		if up.Filename != filename {
+			t.Errorf("uploads[%d].filename = %q, want %q", i, up.Filename, filename)
+		}
+		if up.PhotoID == "" {
+			t.Errorf("uploads[%d] has no photo id", i)
+		}
+		if !strings.Contains(up.URL, up.PhotoID) {
+			t.Errorf("uploads[%d].url = %q, want it signed for photo %s", i, up.URL, up.PhotoID)
+		}
+	}
+	if len(objects) != 2 {
+		t.Fatalf("storage presigned %d objects, want 2", len(objects))
+	}
+	for i, object := range objects {
+		if !strings.HasPrefix(object, testAlbumID+"/") {
+			t.Errorf("objects[%d] = %q, want it keyed under the album", i, object)
+		}
+	}
+}
+
+func TestPresignPhotoUploadsAlbumNotFound(t *testing.T) {
+	handler := newPhotoTestHandler(&mock.AlbumStore{
+		GetAlbumFn: func(context.Context, string, string) (cl.Album, error) {
+			return cl.Album{}, cl.ErrNotFound
+		},
+	}, &mock.PhotoStorage{})
+
+	body := `{"photos":[{"filename":"a.jpg"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:presign", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPresignPhotoUploadsValidation(t *testing.T) {
+	handler := newPhotoTestHandler(&mock.AlbumStore{}, &mock.PhotoStorage{})
+
+	for name, body := range map[string]string{
+		"no photos":      `{"photos":[]}`,
+		"empty filename": `{"photos":[{"filename":""}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:presign", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestPresignPhotoUploadsNoStorage(t *testing.T) {
+	handler := newPhotoTestHandler(&mock.AlbumStore{}, nil)
+
+	body := `{"photos":[{"filename":"a.jpg"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:presign", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", rec.Code)
+	}
+}
+
+func TestConfirmPhotoUploads(t *testing.T) {
+	const photoID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	var gotAlbumID string
+	var gotPhotos []cl.Photo
+	handler := newPhotoTestHandler(&mock.AlbumStore{
+		CreatePhotosFn: func(_ context.Context, albumID string, photos []cl.Photo) ([]cl.Photo, error) {
+			gotAlbumID, gotPhotos = albumID, photos
+			return photos, nil
+		},
+	}, nil)
+
+	body := `{"photos":[{"photo_id":"` + photoID + `","filename":"a.jpg","content_type":"image/jpeg"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:confirm", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if gotAlbumID != testAlbumID {
+		t.Errorf("store received album id %q, want %q", gotAlbumID, testAlbumID)
+	}
+	if len(gotPhotos) != 1 {
+		t.Fatalf("store received %d photos, want 1", len(gotPhotos))
+	}
+	photo := gotPhotos[0]
+	if photo.ID != photoID || photo.AlbumID != testAlbumID || photo.Filename != "a.jpg" {
+		t.Errorf("store received photo %+v, want it linked to the album", photo)
+	}
+	if !photo.ContentType.Valid || photo.ContentType.String != "image/jpeg" {
+		t.Errorf("content type = %+v, want image/jpeg", photo.ContentType)
+	}
+
+	var res cl.ConfirmPhotosRes
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if len(res.Photos) != 1 || res.Photos[0].ID != photoID {
+		t.Errorf("response photos = %+v, want the recorded photo", res.Photos)
+	}
+}
+
+func TestConfirmPhotoUploadsInvalidPhotoID(t *testing.T) {
+	called := false
+	handler := newPhotoTestHandler(&mock.AlbumStore{
+		CreatePhotosFn: func(_ context.Context, _ string, photos []cl.Photo) ([]cl.Photo, error) {
+			called = true
+			return photos, nil
+		},
+	}, nil)
+
+	body := `{"photos":[{"photo_id":"not-a-uuid","filename":"a.jpg"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:confirm", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if called {
+		t.Error("store should not be called for an invalid photo id")
+	}
+}
+
+func TestConfirmPhotoUploadsAlbumNotFound(t *testing.T) {
+	handler := newPhotoTestHandler(&mock.AlbumStore{
+		CreatePhotosFn: func(context.Context, string, []cl.Photo) ([]cl.Photo, error) {
+			return nil, cl.ErrNotFound
+		},
+	}, nil)
+
+	body := `{"photos":[{"photo_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","filename":"a.jpg"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/album/"+testAlbumID+"/photos:confirm", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	httputils "github.com/devchiran/golang-demo/tools/http"
+)
+
+// streamPath is the full path of the SSE endpoint registered below; the
+// timeout middleware exempts it by this path.
+const streamPath = "/v1/albums/stream"
+
+// limits resolves the admission-control knobs, falling back to the
+// historical defaults when unset or non-positive.
+func (h *Handler) limits() (maxConns, concurrent int64) {
+	maxConns, concurrent = h.MaxConnections, h.ConcurrentLimit
+	if maxConns <= 0 {
+		maxConns = 5000
+	}
+	if concurrent <= 0 {
+		concurrent = 250
+	}
+	return maxConns, concurrent
+}
+
+// Handler builds the HTTP handler serving the album API, wrapped in the
+// standard middleware stack.
+func (h *Handler) Handler() http.Handler {
+	r := mux.NewRouter()
+	h.router = r
+
+	r.NotFoundHandler = httputils.NotFoundHandler(h.Logger)
+	r.MethodNotAllowedHandler = httputils.MethodNotAllowedHandler(h.Logger)
+
+	r.HandleFunc("/version", httputils.VersionHandler(h.Name, h.Version)).
+		Methods(http.MethodGet).Name("version")
+
+	if h.DebugRoutes {
+		r.HandleFunc("/debug/routes", h.ListRoutes).Methods(http.MethodGet).Name("debug_routes")
+	}
+
+	if h.Metrics && h.Stats != nil {
+		if mh := h.Stats.Handler(); mh != nil {
+			r.Handle("/metrics", mh).Methods(http.MethodGet).Name("metrics")
+		}
+	}
+
+	if h.DebugPprof {
+		// The explicit routes cover the handlers with dedicated entry
+		// points; Index serves the listing and the named runtime profiles.
+		d := r.PathPrefix("/debug/pprof").Subrouter()
+		d.HandleFunc("/cmdline", pprof.Cmdline).Name("pprof_cmdline")
+		d.HandleFunc("/profile", pprof.Profile).Name("pprof_profile")
+		d.HandleFunc("/symbol", pprof.Symbol).Name("pprof_symbol")
+		d.HandleFunc("/trace", pprof.Trace).Name("pprof_trace")
+		d.PathPrefix("/").HandlerFunc(pprof.Index).Name("pprof_index")
+	}
+
+	v1 := r.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/openapi.json", h.OpenAPI()).Methods(http.MethodGet).Name("openapi")
+	// HEAD rides along on the read routes for monitors and caches;
+	// net/http discards the body, so the handlers need no changes.
+	v1.HandleFunc("/albums", h.ListAlbums).Methods(http.MethodGet, http.MethodHead).Name("list_albums")
+	v1.HandleFunc("/albums/count", h.CountAlbums).Methods(http.MethodGet, http.MethodHead).Name("count_albums")
+	v1.HandleFunc("/albums:delete", h.DeleteAlbums).Methods(http.MethodPost).Name("delete_albums")
+	v1.HandleFunc("/albums/stream", h.StreamAlbumChanges).Methods(http.MethodGet).Name("stream_albums")
+	v1.HandleFunc("/album/{id}", h.GetAlbum).Methods(http.MethodGet, http.MethodHead).Name("get_album")
+	v1.HandleFunc("/album", h.CreateAlbum).Methods(http.MethodPost).Name("create_album")
+	v1.HandleFunc("/album/{id}", h.PatchAlbum).Methods(http.MethodPatch).Name("patch_album")
+	v1.HandleFunc("/album/{id}/cover", h.SetAlbumCover).Methods(http.MethodPut).Name("set_album_cover")
+	v1.HandleFunc("/album/{id}/photos:presign", h.PresignPhotoUploads).Methods(http.MethodPost).Name("presign_photos")
+	v1.HandleFunc("/album/{id}/photos:confirm", h.ConfirmPhotoUploads).Methods(http.MethodPost).Name("confirm_photos")
+
+	if h.Stats != nil {
+		v1.Use(func(next http.Handler) http.Handler {
+			return httputils.StatsRouteMiddleware(h.Stats, next.ServeHTTP)
+		})
+	}
+
+	fn := r.ServeHTTP
+	if h.Stats != nil {
+		fn = httputils.StatsMiddleware(h.Stats, "http", fn)
+		fn = httputils.InFlightMiddleware(h.Stats, "http", fn)
+	}
+	maxConns, concurrent := h.limits()
+	// The SSE stream runs for as long as the client stays connected, so
+	// it is exempt from the request deadline.
+	fn = httputils.TimeoutMiddlewareExcept(1*time.Minute, func(r *http.Request) bool {
+		return r.URL.Path == streamPath
+	}, fn)
+	fn = httputils.ConcurrentLimitMiddleware(concurrent, h.Logger, fn)
+	fn = httputils.MaxConnectionsMiddleware(maxConns, h.Logger, fn)
+	fn = httputils.LimitReaderMiddleware(1<<20, fn)
+	fn = httputils.CORSMiddleware(httputils.CORSConfig{Router: r}, fn)
+	fn = httputils.SecurityHeadersMiddleware(httputils.SecurityConfig{}, fn)
+	fn = httputils.LoggingMiddleware(h.Logger, fn)
+	fn = httputils.RecoverMiddleware(h.Logger, fn)
+	fn = httputils.RequestIDMiddleware(fn)
+	return http.HandlerFunc(fn)
+}
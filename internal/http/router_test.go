@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/mock"
+	"github.com/devchiran/golang-demo/tools"
+)
+
+func TestLimitsFallBackToDefaults(t *testing.T) {
+	maxConns, concurrent := (&Handler{}).limits()
+	if maxConns != 5000 || concurrent != 250 {
+		t.Errorf("got %d/%d, want the 5000/250 defaults", maxConns, concurrent)
+	}
+
+	maxConns, concurrent = (&Handler{MaxConnections: -1, ConcurrentLimit: -1}).limits()
+	if maxConns != 5000 || concurrent != 250 {
+		t.Errorf("negative limits resolved to %d/%d, want the defaults", maxConns, concurrent)
+	}
+
+	maxConns, concurrent = (&Handler{MaxConnections: 12, ConcurrentLimit: 3}).limits()
+	if maxConns != 12 || concurrent != 3 {
+		t.Errorf("got %d/%d, want the configured 12/3", maxConns, concurrent)
+	}
+}
+
+func TestConfiguredMaxConnectionsRejectsBeyondLimit(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	store := &mock.AlbumStore{
+		ListAlbumsFn: func(ctx context.Context, _ cl.ListAlbumsReq) ([]cl.Album, error) {
+			close(entered)
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil, nil
+		},
+	}
+	h := (&Handler{
+		Logger:         &mock.Logger{},
+		AlbumStore:     store,
+		Name:           "test",
+		Version:        "test",
+		MaxConnections: 1,
+	}).Handler()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/albums", nil))
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/albums", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 past the connection limit, got %d", rec.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+// scrapeStats is a no-op stats client whose Handler serves a
+// recognizable body, standing in for the Prometheus exposition handler.
+type scrapeStats struct{ tools.NopStatsClient }
+
+func (scrapeStats) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("# metrics"))
+	})
+}
+
+func TestMetricsAndPprofRoutes(t *testing.T) {
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	enabled := (&Handler{
+		Logger:     &mock.Logger{},
+		Stats:      scrapeStats{},
+		AlbumStore: &mock.AlbumStore{},
+		Name:       "test",
+		Version:    "test",
+		Metrics:    true,
+		DebugPprof: true,
+	}).Handler()
+
+	if rec := get(enabled, "/metrics"); rec.Code != http.StatusOK || rec.Body.String() != "# metrics" {
+		t.Errorf("GET /metrics = %d %q, want the stats handler's output", rec.Code, rec.Body.String())
+	}
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline"} {
+		if rec := get(enabled, path); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+	}
+
+	disabled := (&Handler{
+		Logger:     &mock.Logger{},
+		Stats:      scrapeStats{},
+		AlbumStore: &mock.AlbumStore{},
+		Name:       "test",
+		Version:    "test",
+	}).Handler()
+
+	for _, path := range []string{"/metrics", "/debug/pprof/", "/debug/pprof/cmdline"} {
+		if rec := get(disabled, path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404 while disabled", path, rec.Code)
+		}
+	}
+}
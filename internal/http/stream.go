@@ -0,0 +1,38 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	httputils "github.com/devchiran/golang-demo/tools/http"
+)
+
+// StreamAlbumChanges handles GET /v1/albums/stream, a Server-Sent
+// Events feed carrying the id of every changed album. The stream runs
+// until the client disconnects or the store's subscription ends.
+func (h *Handler) StreamAlbumChanges(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		h.Logger.Error("album stream requested on a non-flushing writer")
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+	ids, err := h.AlbumStore.SubscribeAlbumChanges(r.Context())
+	if err != nil {
+		h.Logger.Error("subscribing to album changes", "error", err.Error())
+		httputils.InternalServerErrorHandler(h.Logger)(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fl.Flush()
+
+	// The channel closes when the request context is cancelled or the
+	// listener shuts down, ending the response either way.
+	for id := range ids {
+		fmt.Fprintf(w, "event: album_changed\ndata: %s\n\n", id)
+		fl.Flush()
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func TestStreamAlbumChanges(t *testing.T) {
+	ids := make(chan string, 2)
+	ids <- "id-1"
+	ids <- "id-2"
+	close(ids)
+	h := newTestHandler(&mock.AlbumStore{
+		SubscribeAlbumChangesFn: func(context.Context) (<-chan string, error) {
+			return ids, nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/albums/stream", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type = %q, want text/event-stream", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"event: album_changed\ndata: id-1\n\n", "event: album_changed\ndata: id-2\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %q is missing %q", body, want)
+		}
+	}
+	if !rec.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+}
+
+func TestStreamAlbumChangesExemptFromTimeout(t *testing.T) {
+	var streamHasDeadline, getHasDeadline bool
+	handler := newTestHandler(&mock.AlbumStore{
+		SubscribeAlbumChangesFn: func(ctx context.Context) (<-chan string, error) {
+			_, streamHasDeadline = ctx.Deadline()
+			ids := make(chan string)
+			close(ids)
+			return ids, nil
+		},
+		GetAlbumFn: func(ctx context.Context, _, id string) (cl.Album, error) {
+			_, getHasDeadline = ctx.Deadline()
+			return cl.Album{ID: id}, nil
+		},
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/albums/stream", nil))
+	if streamHasDeadline {
+		t.Error("expected the stream handler to run without a deadline")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/album/9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", nil))
+	if !getHasDeadline {
+		t.Error("expected ordinary handlers to keep the request deadline")
+	}
+}
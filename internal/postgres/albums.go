@@ -0,0 +1,437 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/tools/postgres"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// escapeLike escapes the LIKE metacharacters in s so user input matches
+// literally. Postgres uses backslash as the default escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// photoCountJoin aggregates photos per album; COALESCE keeps the count
+// at zero, not NULL, for albums without photos.
+const (
+	photoCountColumn = "COALESCE(pc.photo_count, 0) AS photo_count"
+	photoCountJoin   = "(SELECT album_id, COUNT(*) AS photo_count FROM photos WHERE deleted_at IS NULL GROUP BY album_id) pc ON pc.album_id = a.id"
+
+	// notDeleted excludes soft-deleted albums; every query appends it
+	// unless the caller asked for deleted rows.
+	notDeleted = "a.deleted_at IS NULL"
+)
+
+// orgScope matches albums owned by orgID. An empty org matches only
+// albums without an organization, so tenants never see each other's
+// rows.
+func orgScope(orgID string) sq.Sqlizer {
+	if orgID == "" {
+		return sq.Expr("a.org_id IS NULL")
+	}
+	return sq.Eq{"a.org_id": orgID}
+}
+
+// sortColumns whitelists the sortable columns; ORDER BY is built only
+// from these expressions, never from raw user input.
+var sortColumns = map[string]string{
+	"created_at": "a.created_at",
+	"updated_at": "a.updated_at",
+	"title":      "a.title",
+}
+
+// orderBy maps a validated sort value onto ORDER BY clauses. The id
+// tiebreak keeps the order total, so keyset pagination never skips or
+// repeats rows with equal sort keys.
+func orderBy(sort string) []string {
+	col, dir := sort, " ASC"
+	if strings.HasPrefix(sort, "-") {
+		col, dir = sort[1:], " DESC"
+	}
+	expr, ok := sortColumns[col]
+	if !ok {
+		return []string{"a.created_at DESC", "a.id DESC"}
+	}
+	return []string{expr + dir, "a.id" + dir}
+}
+
+func buildListAlbumsQuery(req cl.ListAlbumsReq) (string, []interface{}, error) {
+	b := psql.Select("a.id", "a.title", "a.created_at", "a.updated_at", "a.deleted_at", "a.cover_photo_id", "a.org_id", photoCountColumn).
+		From("albums a").
+		LeftJoin(photoCountJoin).
+		Where(orgScope(req.OrgID)).
+		OrderBy(orderBy(req.Sort)...).
+		Limit(uint64(req.Limit))
+	if !req.IncludeDeleted {
+		b = b.Where(notDeleted)
+	}
+	if req.Cursor != nil {
+		b = b.Where("(a.created_at, a.id) < (?, ?)", req.Cursor.CreatedAt, req.Cursor.ID)
+	} else {
+		b = b.Offset(uint64(req.Offset))
+	}
+	if req.StartDate.Valid {
+		b = b.Where(sq.GtOrEq{"a.created_at": req.StartDate.Time})
+	}
+	if req.EndDate.Valid {
+		b = b.Where(sq.LtOrEq{"a.created_at": req.EndDate.Time})
+	}
+	if req.Query != "" {
+		b = b.Where("a.title ILIKE '%' || ? || '%'", escapeLike(req.Query))
+	}
+	return b.ToSql()
+}
+
+// ListAlbums returns the albums matching req, newest first.
+func (p *Postgres) ListAlbums(ctx context.Context, req cl.ListAlbumsReq) ([]cl.Album, error) {
+	query, args, err := buildListAlbumsQuery(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "building list albums query")
+	}
+	albums := []cl.Album{}
+	err = p.readDB().Do(ctx, "list_albums", func(ctx context.Context, c postgres.Conn) error {
+		return c.QueryStructsPrepared(ctx, &albums, query, args...)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing albums")
+	}
+	return albums, nil
+}
+
+func buildCountAlbumsQuery(req cl.CountAlbumsReq) (string, []interface{}, error) {
+	b := psql.Select("COUNT(*)").
+		From("albums a").
+		Where(orgScope(req.OrgID)).
+		Where(notDeleted)
+	if req.StartDate.Valid {
+		b = b.Where(sq.GtOrEq{"a.created_at": req.StartDate.Time})
+	}
+	if req.EndDate.Valid {
+		b = b.Where(sq.LtOrEq{"a.created_at": req.EndDate.Time})
+	}
+	if req.Query != "" {
+		b = b.Where("a.title ILIKE '%' || ? || '%'", escapeLike(req.Query))
+	}
+	return b.ToSql()
+}
+
+// CountAlbums returns how many albums match req without fetching any
+// rows.
+func (p *Postgres) CountAlbums(ctx context.Context, req cl.CountAlbumsReq) (int, error) {
+	query, args, err := buildCountAlbumsQuery(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "building count albums query")
+	}
+	var count int
+	err = p.readDB().Do(ctx, "count_albums", func(ctx context.Context, c postgres.Conn) error {
+		row, err := c.QueryRow(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		return row.Scan(&count)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "counting albums")
+	}
+	return count, nil
+}
+
+func buildGetAlbumQuery(orgID, id string, includeDeleted bool) (string, []interface{}, error) {
+	b := psql.Select("a.id", "a.title", "a.created_at", "a.updated_at", "a.deleted_at", "a.cover_photo_id", "a.org_id", photoCountColumn).
+		From("albums a").
+		LeftJoin(photoCountJoin).
+		Where(sq.Eq{"a.id": id}).
+		Where(orgScope(orgID))
+	if !includeDeleted {
+		b = b.Where(notDeleted)
+	}
+	return b.ToSql()
+}
+
+// GetAlbum returns orgID's album with the given id, or cl.ErrNotFound.
+// Soft-deleted albums and albums of other organizations are both
+// treated as absent, so existence never leaks across tenants. With
+// WithSingleflight enabled, concurrent calls for the same album share
+// one query; the key includes the organization, so tenants never share
+// results.
+func (p *Postgres) GetAlbum(ctx context.Context, orgID, id string) (cl.Album, error) {
+	if p.sf == nil {
+		return p.getAlbum(ctx, orgID, id)
+	}
+	v, err, _ := p.sf.Do(orgID+"/"+id, func() (interface{}, error) {
+		return p.getAlbum(ctx, orgID, id)
+	})
+	if err != nil {
+		return cl.Album{}, err
+	}
+	return v.(cl.Album), nil
+}
+
+func (p *Postgres) getAlbum(ctx context.Context, orgID, id string) (cl.Album, error) {
+	// Deleted rows are fetched too, then told apart below: a row with
+	// deleted_at set becomes cl.ErrGone, no row at all cl.ErrNotFound.
+	query, args, err := buildGetAlbumQuery(orgID, id, true)
+	if err != nil {
+		return cl.Album{}, errors.Wrap(err, "building get album query")
+	}
+	var album cl.Album
+	err = p.readDB().Do(ctx, "get_album", func(ctx context.Context, c postgres.Conn) error {
+		if err := c.QueryStructPrepared(ctx, &album, query, args...); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return cl.ErrNotFound
+			}
+			return err
+		}
+		if album.DeletedAt.Valid {
+			return cl.ErrGone
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, cl.ErrNotFound) || errors.Is(err, cl.ErrGone) {
+			return cl.Album{}, err
+		}
+		return cl.Album{}, errors.Wrap(err, "getting album")
+	}
+	return album, nil
+}
+
+func buildPatchAlbumQuery(id string, req cl.PatchAlbumRequest) (string, []interface{}, error) {
+	b := psql.Update("albums").
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NULL").
+		// A subselect keeps the returned photo count consistent with
+		// GetAlbum without a second round trip.
+		Suffix("RETURNING id, title, created_at, updated_at, deleted_at, cover_photo_id, org_id, (SELECT COUNT(*) FROM photos WHERE album_id = albums.id AND deleted_at IS NULL) AS photo_count")
+	if req.Title != nil {
+		b = b.Set("title", *req.Title)
+	}
+	return b.ToSql()
+}
+
+// PatchAlbum updates only the fields present in req and returns the
+// resulting album, or cl.ErrNotFound.
+func (p *Postgres) PatchAlbum(ctx context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error) {
+	query, args, err := buildPatchAlbumQuery(id, req)
+	if err != nil {
+		return cl.Album{}, errors.Wrap(err, "building patch album query")
+	}
+	var album cl.Album
+	err = p.db.Do(ctx, "patch_album", func(ctx context.Context, c postgres.Conn) error {
+		row, err := c.QueryRow(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if err := row.Scan(&album.ID, &album.Title, &album.CreatedAt, &album.UpdatedAt, &album.DeletedAt, &album.CoverPhotoID, &album.OrgID, &album.PhotoCount); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return cl.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, cl.ErrNotFound) {
+			return cl.Album{}, err
+		}
+		return cl.Album{}, errors.Wrap(err, "patching album")
+	}
+	return album, nil
+}
+
+func buildSoftDeleteAlbumQuery(id string) (string, []interface{}, error) {
+	return psql.Update("albums").
+		Set("deleted_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NULL").
+		ToSql()
+}
+
+// SoftDeleteAlbum marks the album deleted without removing the row.
+// Deleting an absent or already-deleted album returns cl.ErrNotFound.
+func (p *Postgres) SoftDeleteAlbum(ctx context.Context, id string) error {
+	query, args, err := buildSoftDeleteAlbumQuery(id)
+	if err != nil {
+		return errors.Wrap(err, "building soft delete album query")
+	}
+	err = p.db.Do(ctx, "soft_delete_album", func(ctx context.Context, c postgres.Conn) error {
+		res, err := c.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return cl.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, cl.ErrNotFound) {
+			return err
+		}
+		return errors.Wrap(err, "soft deleting album")
+	}
+	return nil
+}
+
+// DeleteAlbums hard-deletes the given albums and their photos in one
+// transaction and returns how many albums were removed. Ids that match
+// nothing are skipped, so the count may be lower than len(ids). Unlike
+// SoftDeleteAlbum this removes the rows for good; it exists for bulk
+// cleanup, not for the API's regular delete flow.
+func (p *Postgres) DeleteAlbums(ctx context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	photosClause, photosArgs := postgres.InClause("album_id", args, 0)
+	albumsClause, albumsArgs := postgres.InClause("id", args, 0)
+
+	var deleted int64
+	err := p.db.Do(ctx, "delete_albums", func(ctx context.Context, _ postgres.Conn) error {
+		// The prepared-statement cache would grow an entry per distinct
+		// id count, so the transaction runs on the raw pool instead.
+		tx, err := p.db.DB().BeginTx(ctx, nil)
+		if err != nil {
+			return errors.Wrap(err, "beginning transaction")
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM photos WHERE "+photosClause, photosArgs...); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return errors.Wrap(err, "deleting photos")
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM albums WHERE "+albumsClause, albumsArgs...)
+		if err != nil {
+			tx.Rollback() //nolint:errcheck
+			return errors.Wrap(err, "deleting albums")
+		}
+		if deleted, err = res.RowsAffected(); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return err
+		}
+		return errors.Wrap(tx.Commit(), "committing delete")
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "deleting albums")
+	}
+	return int(deleted), nil
+}
+
+func buildSetAlbumCoverQuery(albumID, photoID string) (string, []interface{}, error) {
+	return psql.Update("albums").
+		Set("cover_photo_id", photoID).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": albumID}).
+		Where("deleted_at IS NULL").
+		Suffix("RETURNING id, title, created_at, updated_at, deleted_at, cover_photo_id, org_id, (SELECT COUNT(*) FROM photos WHERE album_id = albums.id AND deleted_at IS NULL) AS photo_count").
+		ToSql()
+}
+
+// SetAlbumCover sets the album's cover photo and returns the updated
+// album. The photo must belong to the album; pointing at a photo from
+// another album (or none at all) returns cl.ErrPhotoNotInAlbum, and an
+// absent album returns cl.ErrNotFound.
+func (p *Postgres) SetAlbumCover(ctx context.Context, albumID, photoID string) (cl.Album, error) {
+	query, args, err := buildSetAlbumCoverQuery(albumID, photoID)
+	if err != nil {
+		return cl.Album{}, errors.Wrap(err, "building set album cover query")
+	}
+	var album cl.Album
+	err = p.db.Do(ctx, "set_album_cover", func(ctx context.Context, c postgres.Conn) error {
+		// Resolve the photo's album first so a mismatch surfaces as a
+		// validation failure rather than a silent no-op.
+		row, err := c.QueryRow(ctx, "SELECT album_id FROM photos WHERE id = $1 AND deleted_at IS NULL", photoID)
+		if err != nil {
+			return err
+		}
+		var photoAlbumID string
+		if err := row.Scan(&photoAlbumID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return cl.ErrPhotoNotInAlbum
+			}
+			return err
+		}
+		if photoAlbumID != albumID {
+			return cl.ErrPhotoNotInAlbum
+		}
+		row, err = c.QueryRow(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if err := row.Scan(&album.ID, &album.Title, &album.CreatedAt, &album.UpdatedAt, &album.DeletedAt, &album.CoverPhotoID, &album.OrgID, &album.PhotoCount); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return cl.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, cl.ErrNotFound) || errors.Is(err, cl.ErrPhotoNotInAlbum) {
+			return cl.Album{}, err
+		}
+		return cl.Album{}, errors.Wrap(err, "setting album cover")
+	}
+	return album, nil
+}
+
+func buildCreateAlbumQuery(id string, req cl.CreateAlbumRequest) (string, []interface{}, error) {
+	// now() is stable within a statement, so created_at and updated_at
+	// start out identical. A supplied created_at (validated handler-side)
+	// is preserved for migrated data, with updated_at matching it.
+	createdAt := interface{}(sq.Expr("now()"))
+	if req.CreatedAt.Valid {
+		createdAt = req.CreatedAt.Time
+	}
+	var orgID interface{}
+	if req.OrgID != "" {
+		orgID = req.OrgID
+	}
+	return psql.Insert("albums").
+		Columns("id", "title", "org_id", "created_at", "updated_at").
+		Values(id, req.Title, orgID, createdAt, createdAt).
+		Suffix("RETURNING id, title, org_id, created_at, updated_at").
+		ToSql()
+}
+
+// CreateAlbum inserts a new album and returns it.
+func (p *Postgres) CreateAlbum(ctx context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return cl.Album{}, errors.Wrap(err, "generating album id")
+	}
+	query, args, err := buildCreateAlbumQuery(id.String(), req)
+	if err != nil {
+		return cl.Album{}, errors.Wrap(err, "building create album query")
+	}
+	var album cl.Album
+	err = p.db.Do(ctx, "create_album", func(ctx context.Context, c postgres.Conn) error {
+		row, err := c.QueryRow(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		return row.Scan(&album.ID, &album.Title, &album.OrgID, &album.CreatedAt, &album.UpdatedAt)
+	})
+	if err != nil {
+		// Classify keeps constraint violations distinguishable so the
+		// handler can map them onto conflict responses.
+		return cl.Album{}, errors.Wrap(postgres.Classify(err), "creating album")
+	}
+	return album, nil
+}
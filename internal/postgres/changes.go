@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// albumsChangedChannel is the NOTIFY channel the albums_changed_notify
+// trigger publishes album ids on.
+const albumsChangedChannel = "albums_changed"
+
+// SubscribeAlbumChanges forwards the id of every inserted, updated or
+// deleted album until ctx is cancelled or the listener closes; the
+// returned channel is closed in either case. It requires a store built
+// with NewWithListener.
+//
+// Notifications are consumed from the single underlying listener, so
+// run one subscription per store; fanning out to many consumers is the
+// caller's job.
+func (p *Postgres) SubscribeAlbumChanges(ctx context.Context) (<-chan string, error) {
+	if p.listener == nil {
+		return nil, errors.New("store has no listener; use NewWithListener")
+	}
+	if err := p.listener.Listen(albumsChangedChannel); err != nil {
+		return nil, errors.Wrap(err, "subscribing to album changes")
+	}
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		defer p.listener.Unlisten(albumsChangedChannel) //nolint:errcheck // dropping a dead listener
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n, ok := <-p.listener.NotificationChannel():
+				if !ok {
+					return
+				}
+				// pq delivers a nil notification after a reconnect.
+				if n == nil || n.Channel != albumsChangedChannel {
+					continue
+				}
+				select {
+				case ids <- n.Extra:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ids, nil
+}
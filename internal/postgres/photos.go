@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/tools/postgres"
+)
+
+func buildCreatePhotosQuery(photos []cl.Photo) (string, []interface{}, error) {
+	b := psql.Insert("photos").
+		Columns("id", "album_id", "filename", "content_type").
+		Suffix("RETURNING id, album_id, filename, content_type, created_at")
+	for _, photo := range photos {
+		b = b.Values(photo.ID, photo.AlbumID, photo.Filename, photo.ContentType)
+	}
+	return b.ToSql()
+}
+
+// CreatePhotos inserts the given photos into the album in one batch and
+// returns the stored rows. An absent or soft-deleted album returns
+// cl.ErrNotFound.
+func (p *Postgres) CreatePhotos(ctx context.Context, albumID string, photos []cl.Photo) ([]cl.Photo, error) {
+	query, args, err := buildCreatePhotosQuery(photos)
+	if err != nil {
+		return nil, errors.Wrap(err, "building create photos query")
+	}
+	created := make([]cl.Photo, 0, len(photos))
+	err = p.db.Do(ctx, "create_photos", func(ctx context.Context, c postgres.Conn) error {
+		// Check the album first so a missing one surfaces as not found
+		// rather than a foreign key violation.
+		row, err := c.QueryRow(ctx, "SELECT 1 FROM albums WHERE id = $1 AND deleted_at IS NULL", albumID)
+		if err != nil {
+			return err
+		}
+		var one int
+		if err := row.Scan(&one); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return cl.ErrNotFound
+			}
+			return err
+		}
+		rows, err := c.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close() //nolint:errcheck
+		for rows.Next() {
+			var photo cl.Photo
+			if err := rows.Scan(&photo.ID, &photo.AlbumID, &photo.Filename, &photo.ContentType, &photo.CreatedAt); err != nil {
+				return err
+			}
+			created = append(created, photo)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		if errors.Is(err, cl.ErrNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrap(postgres.Classify(err), "creating photos")
+	}
+	return created, nil
+}
@@ -0,0 +1,73 @@
+// Package postgres implements the album store on PostgreSQL.
+package postgres
+
+import (
+	"golang.org/x/sync/singleflight"
+
+	"github.com/devchiran/golang-demo/tools/postgres"
+)
+
+// Postgres stores albums in PostgreSQL.
+type Postgres struct {
+	db       *postgres.DB
+	replica  *postgres.DB
+	listener *postgres.Listener
+	sf       *singleflight.Group
+}
+
+// Option tunes the store built by New.
+type Option func(*Postgres)
+
+// WithReplica routes the read-only album queries — ListAlbums, GetAlbum
+// and CountAlbums — to a replica pool, keeping writes on the primary.
+// Replica reads may lag replication slightly; flows that must read
+// their own writes should stay on a store without one.
+func WithReplica(db *postgres.DB) Option {
+	return func(p *Postgres) { p.replica = db }
+}
+
+// WithSingleflight collapses concurrent GetAlbum calls for the same
+// album into a single database query whose result they all share,
+// taking the stampede off hot albums. The shared query runs under the
+// first caller's context.
+func WithSingleflight() Option {
+	return func(p *Postgres) { p.sf = new(singleflight.Group) }
+}
+
+// New wraps an opened connection pool.
+func New(db *postgres.DB, opts ...Option) *Postgres {
+	p := &Postgres{db: db}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewWithListener is New with a notification listener attached, which
+// enables change subscriptions like SubscribeAlbumChanges.
+func NewWithListener(db *postgres.DB, l *postgres.Listener, opts ...Option) *Postgres {
+	p := New(db, opts...)
+	p.listener = l
+	return p
+}
+
+// readDB is the pool read-only queries run on: the replica when one is
+// configured, the primary otherwise.
+func (p *Postgres) readDB() *postgres.DB {
+	if p.replica != nil {
+		return p.replica
+	}
+	return p.db
+}
+
+// Close releases the pools, their cached prepared statements and the
+// listener, when one is attached. The store is unusable afterwards.
+func (p *Postgres) Close() error {
+	if p.listener != nil {
+		p.listener.Close() //nolint:errcheck // the pool error matters more
+	}
+	if p.replica != nil {
+		p.replica.Close() //nolint:errcheck // the primary error matters more
+	}
+	return p.db.Close()
+}
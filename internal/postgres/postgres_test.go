@@ -0,0 +1,748 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+	"github.com/devchiran/golang-demo/tools/postgres"
+)
+
+// testStore bundles the store under test with a raw sqlx handle for
+// fixture setup and cleanup.
+type testStore struct {
+	*Postgres
+	sqlx *sqlx.DB
+}
+
+// newTestPostgres opens a store on the test database; see
+// testdb_test.go for how that database is located and migrated.
+func newTestPostgres(t *testing.T) *testStore {
+	t.Helper()
+	db := openTestDB(t)
+	return &testStore{Postgres: New(db), sqlx: sqlx.NewDb(db.DB(), "postgres")}
+}
+
+// createTestAlbum inserts an album and removes it again when the test
+// finishes.
+func createTestAlbum(t *testing.T, p *testStore, title string) cl.Album {
+	t.Helper()
+	album, err := p.CreateAlbum(context.Background(), cl.CreateAlbumRequest{Title: title})
+	if err != nil {
+		t.Fatalf("creating album %q: %v", title, err)
+	}
+	t.Cleanup(func() {
+		p.sqlx.MustExec("DELETE FROM albums WHERE id = $1", album.ID)
+	})
+	return album
+}
+
+// createTestPhoto inserts a photo into album, removes it again when the
+// test finishes, and returns its id.
+func createTestPhoto(t *testing.T, p *testStore, albumID, filename string) string {
+	t.Helper()
+	var id string
+	err := p.sqlx.Get(&id,
+		"INSERT INTO photos (id, album_id, filename, content_type) VALUES (gen_random_uuid(), $1, $2, 'image/jpeg') RETURNING id",
+		albumID, filename,
+	)
+	if err != nil {
+		t.Fatalf("creating photo %q: %v", filename, err)
+	}
+	t.Cleanup(func() {
+		p.sqlx.MustExec("DELETE FROM photos WHERE id = $1", id)
+	})
+	return id
+}
+
+func TestCreateAlbumSetsUpdatedAt(t *testing.T) {
+	p := newTestPostgres(t)
+
+	album := createTestAlbum(t, p, "Timestamps")
+	if album.UpdatedAt.IsZero() {
+		t.Fatal("expected a non-zero updated_at on a freshly created album")
+	}
+	if !album.UpdatedAt.Equal(album.CreatedAt.Time) {
+		t.Errorf("expected updated_at %v to equal created_at %v", album.UpdatedAt, album.CreatedAt)
+	}
+}
+
+func TestCloseStopsQueries(t *testing.T) {
+	p := newTestPostgres(t)
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("closing store: %v", err)
+	}
+	if _, err := p.GetAlbum(context.Background(), "", "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"); err == nil {
+		t.Fatal("expected queries to fail after Close")
+	}
+}
+
+func TestPatchAlbumTitleOnly(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	album := createTestAlbum(t, p, "Before")
+	title := "After"
+	got, err := p.PatchAlbum(ctx, album.ID, cl.PatchAlbumRequest{Title: &title})
+	if err != nil {
+		t.Fatalf("patching album: %v", err)
+	}
+	if got.Title != title {
+		t.Errorf("title = %q, want %q", got.Title, title)
+	}
+	if !got.CreatedAt.Equal(album.CreatedAt.Time) {
+		t.Errorf("created_at changed from %v to %v", album.CreatedAt, got.CreatedAt)
+	}
+	if !got.UpdatedAt.After(album.UpdatedAt.Time) {
+		t.Errorf("expected updated_at %v to move past %v", got.UpdatedAt, album.UpdatedAt)
+	}
+}
+
+func TestPatchAlbumNotFound(t *testing.T) {
+	p := newTestPostgres(t)
+
+	title := "Anything"
+	_, err := p.PatchAlbum(context.Background(), "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", cl.PatchAlbumRequest{Title: &title})
+	if err != cl.ErrNotFound {
+		t.Fatalf("expected cl.ErrNotFound, got %v", err)
+	}
+}
+
+func TestSoftDeleteAlbum(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	album := createTestAlbum(t, p, "softdelete target")
+	keeper := createTestAlbum(t, p, "softdelete keeper")
+
+	if err := p.SoftDeleteAlbum(ctx, album.ID); err != nil {
+		t.Fatalf("soft deleting album: %v", err)
+	}
+
+	t.Run("get reports deleted as gone", func(t *testing.T) {
+		if _, err := p.GetAlbum(ctx, "", album.ID); !errors.Is(err, cl.ErrGone) {
+			t.Errorf("expected cl.ErrGone, got %v", err)
+		}
+	})
+
+	t.Run("get treats unknown ids as not found", func(t *testing.T) {
+		if _, err := p.GetAlbum(ctx, "", "00000000-0000-0000-0000-000000000000"); !errors.Is(err, cl.ErrNotFound) {
+			t.Errorf("expected cl.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("excluded from normal listings", func(t *testing.T) {
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 10, Query: "softdelete"})
+		if err != nil {
+			t.Fatalf("listing albums: %v", err)
+		}
+		if len(albums) != 1 || albums[0].ID != keeper.ID {
+			t.Errorf("expected only the keeper, got %v", albums)
+		}
+	})
+
+	t.Run("included when requested", func(t *testing.T) {
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 10, Query: "softdelete", IncludeDeleted: true})
+		if err != nil {
+			t.Fatalf("listing albums: %v", err)
+		}
+		if len(albums) != 2 {
+			t.Fatalf("expected both albums, got %d", len(albums))
+		}
+		for _, a := range albums {
+			if a.ID == album.ID && !a.DeletedAt.Valid {
+				t.Error("expected the deleted album to carry deleted_at")
+			}
+			if a.ID == keeper.ID && a.DeletedAt.Valid {
+				t.Error("expected the keeper to have a null deleted_at")
+			}
+		}
+	})
+
+	t.Run("patching a deleted album is a not-found", func(t *testing.T) {
+		title := "Anything"
+		if _, err := p.PatchAlbum(ctx, album.ID, cl.PatchAlbumRequest{Title: &title}); !errors.Is(err, cl.ErrNotFound) {
+			t.Errorf("expected cl.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("deleting twice is a not-found", func(t *testing.T) {
+		if err := p.SoftDeleteAlbum(ctx, album.ID); !errors.Is(err, cl.ErrNotFound) {
+			t.Errorf("expected cl.ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestAlbumPhotoCount(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	full := createTestAlbum(t, p, "Full Album")
+	empty := createTestAlbum(t, p, "Empty Album")
+	createTestPhoto(t, p, full.ID, "one.jpg")
+	createTestPhoto(t, p, full.ID, "two.jpg")
+
+	t.Run("get album", func(t *testing.T) {
+		got, err := p.GetAlbum(ctx, "", full.ID)
+		if err != nil {
+			t.Fatalf("getting album: %v", err)
+		}
+		if got.PhotoCount != 2 {
+			t.Errorf("photo count = %d, want 2", got.PhotoCount)
+		}
+	})
+
+	t.Run("empty album counts zero", func(t *testing.T) {
+		got, err := p.GetAlbum(ctx, "", empty.ID)
+		if err != nil {
+			t.Fatalf("getting album: %v", err)
+		}
+		if got.PhotoCount != 0 {
+			t.Errorf("photo count = %d, want 0", got.PhotoCount)
+		}
+	})
+
+	t.Run("list albums", func(t *testing.T) {
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 100})
+		if err != nil {
+			t.Fatalf("listing albums: %v", err)
+		}
+		counts := make(map[string]int, len(albums))
+		for _, a := range albums {
+			counts[a.ID] = a.PhotoCount
+		}
+		if counts[full.ID] != 2 {
+			t.Errorf("photo count for %s = %d, want 2", full.Title, counts[full.ID])
+		}
+		if counts[empty.ID] != 0 {
+			t.Errorf("photo count for %s = %d, want 0", empty.Title, counts[empty.ID])
+		}
+	})
+}
+
+func TestListAlbumsSortOrder(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	createTestAlbum(t, p, "sorttest B")
+	createTestAlbum(t, p, "sorttest A")
+	createTestAlbum(t, p, "sorttest C")
+
+	titles := func(sort string) []string {
+		t.Helper()
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 10, Query: "sorttest", Sort: sort})
+		if err != nil {
+			t.Fatalf("listing with sort=%q: %v", sort, err)
+		}
+		got := make([]string, len(albums))
+		for i, a := range albums {
+			got[i] = a.Title
+		}
+		return got
+	}
+
+	asc := titles("title")
+	if len(asc) != 3 || asc[0] != "sorttest A" || asc[1] != "sorttest B" || asc[2] != "sorttest C" {
+		t.Errorf("sort=title yielded %v, want ascending titles", asc)
+	}
+	desc := titles("-title")
+	if len(desc) != 3 || desc[0] != "sorttest C" || desc[2] != "sorttest A" {
+		t.Errorf("sort=-title yielded %v, want descending titles", desc)
+	}
+}
+
+func TestListAlbumsCursorPagination(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	want := make(map[string]bool)
+	for _, title := range []string{"Page A", "Page B", "Page C", "Page D", "Page E"} {
+		want[createTestAlbum(t, p, title).ID] = false
+	}
+
+	var cursor *cl.Cursor
+	seen := make(map[string]int)
+	for page := 0; page < 10; page++ {
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("listing page %d: %v", page, err)
+		}
+		if len(albums) == 0 {
+			break
+		}
+		for _, a := range albums {
+			seen[a.ID]++
+		}
+		last := albums[len(albums)-1]
+		cursor = &cl.Cursor{CreatedAt: last.CreatedAt.Time, ID: last.ID}
+	}
+
+	for id := range want {
+		if seen[id] != 1 {
+			t.Errorf("album %s seen %d times, want exactly once", id, seen[id])
+		}
+	}
+	for id, n := range seen {
+		if n > 1 {
+			t.Errorf("album %s repeated across pages (%d times)", id, n)
+		}
+	}
+}
+
+func TestListAlbumsTitleSearch(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	holiday := createTestAlbum(t, p, "Summer Holiday")
+	createTestAlbum(t, p, "Wedding")
+	percent := createTestAlbum(t, p, "100% Done")
+
+	listTitles := func(q string) map[string]bool {
+		t.Helper()
+		albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{Limit: 100, Query: q})
+		if err != nil {
+			t.Fatalf("listing albums with q=%q: %v", q, err)
+		}
+		got := make(map[string]bool, len(albums))
+		for _, a := range albums {
+			got[a.ID] = true
+		}
+		return got
+	}
+
+	t.Run("match is case-insensitive and partial", func(t *testing.T) {
+		got := listTitles("HOLIDAY")
+		if !got[holiday.ID] {
+			t.Errorf("expected album %q in results", holiday.Title)
+		}
+		if len(got) != 1 {
+			t.Errorf("expected exactly one match, got %d", len(got))
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		if got := listTitles("does-not-exist"); len(got) != 0 {
+			t.Errorf("expected no matches, got %d", len(got))
+		}
+	})
+
+	t.Run("like metacharacters match literally", func(t *testing.T) {
+		got := listTitles("100%")
+		if !got[percent.ID] {
+			t.Errorf("expected album %q in results", percent.Title)
+		}
+		if len(got) != 1 {
+			t.Errorf("expected exactly one match, got %d", len(got))
+		}
+		// "_" must not act as a single-character wildcard.
+		if got := listTitles("Weddin_"); len(got) != 0 {
+			t.Errorf("expected underscore to match literally, got %d results", len(got))
+		}
+	})
+
+	t.Run("empty query applies no filter", func(t *testing.T) {
+		got := listTitles("")
+		for _, id := range []string{holiday.ID, percent.ID} {
+			if !got[id] {
+				t.Errorf("expected album %s in unfiltered results", id)
+			}
+		}
+	})
+}
+
+func TestSetAlbumCover(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	album := createTestAlbum(t, p, "cover album")
+	other := createTestAlbum(t, p, "other album")
+	photoID := createTestPhoto(t, p, album.ID, "cover.jpg")
+	otherPhotoID := createTestPhoto(t, p, other.ID, "stranger.jpg")
+
+	t.Run("sets a photo from the album", func(t *testing.T) {
+		got, err := p.SetAlbumCover(ctx, album.ID, photoID)
+		if err != nil {
+			t.Fatalf("setting cover: %v", err)
+		}
+		if !got.CoverPhotoID.Valid || got.CoverPhotoID.String != photoID {
+			t.Errorf("cover_photo_id = %v, want %s", got.CoverPhotoID, photoID)
+		}
+		fetched, err := p.GetAlbum(ctx, "", album.ID)
+		if err != nil {
+			t.Fatalf("getting album: %v", err)
+		}
+		if !fetched.CoverPhotoID.Valid || fetched.CoverPhotoID.String != photoID {
+			t.Errorf("fetched cover_photo_id = %v, want %s", fetched.CoverPhotoID, photoID)
+		}
+	})
+
+	t.Run("rejects a photo from another album", func(t *testing.T) {
+		if _, err := p.SetAlbumCover(ctx, album.ID, otherPhotoID); !errors.Is(err, cl.ErrPhotoNotInAlbum) {
+			t.Errorf("err = %v, want cl.ErrPhotoNotInAlbum", err)
+		}
+	})
+
+	t.Run("rejects an unknown photo", func(t *testing.T) {
+		if _, err := p.SetAlbumCover(ctx, album.ID, "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d"); !errors.Is(err, cl.ErrPhotoNotInAlbum) {
+			t.Errorf("err = %v, want cl.ErrPhotoNotInAlbum", err)
+		}
+	})
+}
+
+func TestCreateAlbumSuppliedTimestamp(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	supplied := time.Date(2019, 3, 14, 9, 26, 53, 0, time.UTC)
+	album, err := p.CreateAlbum(ctx, cl.CreateAlbumRequest{
+		Title:     "Migrated Album",
+		CreatedAt: null.TimeFrom(supplied),
+	})
+	if err != nil {
+		t.Fatalf("creating album: %v", err)
+	}
+	t.Cleanup(func() {
+		p.sqlx.MustExec("DELETE FROM albums WHERE id = $1", album.ID)
+	})
+
+	if !album.CreatedAt.Equal(supplied) {
+		t.Errorf("created_at = %v, want the supplied %v", album.CreatedAt, supplied)
+	}
+	if !album.UpdatedAt.Equal(supplied) {
+		t.Errorf("updated_at = %v, want to match the supplied created_at", album.UpdatedAt)
+	}
+
+	defaulted := createTestAlbum(t, p, "Fresh Album")
+	if defaulted.CreatedAt.Equal(supplied) || time.Since(defaulted.CreatedAt.Time) > time.Minute {
+		t.Errorf("created_at without a supplied value = %v, want roughly now", defaulted.CreatedAt)
+	}
+}
+
+func TestSubscribeAlbumChanges(t *testing.T) {
+	p := newTestPostgres(t)
+	p.listener = postgres.NewListener(testDatabaseOptions(t))
+	t.Cleanup(func() { p.listener.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	ids, err := p.SubscribeAlbumChanges(ctx)
+	if err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+
+	album := createTestAlbum(t, p, "notify me")
+
+	// Other tests (and the album's own cleanup) may also fire changes;
+	// wait for this album's id specifically.
+	for {
+		select {
+		case id, ok := <-ids:
+			if !ok {
+				t.Fatal("channel closed before the created album was observed")
+			}
+			if id == album.ID {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the album change notification")
+		}
+	}
+}
+
+func TestCreatePhotos(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	album := createTestAlbum(t, p, "bulk upload")
+	photos := []cl.Photo{
+		{ID: "9c8318f5-43a8-4fd4-8c1c-8a3c67f4de01", AlbumID: album.ID, Filename: "a.jpg", ContentType: null.StringFrom("image/jpeg")},
+		{ID: "9c8318f5-43a8-4fd4-8c1c-8a3c67f4de02", AlbumID: album.ID, Filename: "b.png"},
+	}
+	t.Cleanup(func() {
+		p.sqlx.MustExec("DELETE FROM photos WHERE album_id = $1", album.ID)
+	})
+
+	created, err := p.CreatePhotos(ctx, album.ID, photos)
+	if err != nil {
+		t.Fatalf("creating photos: %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("got %d photos, want 2", len(created))
+	}
+	for i, photo := range created {
+		if photo.AlbumID != album.ID {
+			t.Errorf("photos[%d].album_id = %q, want %q", i, photo.AlbumID, album.ID)
+		}
+		if photo.CreatedAt.IsZero() {
+			t.Errorf("photos[%d] has a zero created_at", i)
+		}
+	}
+	if !created[0].ContentType.Valid || created[0].ContentType.String != "image/jpeg" {
+		t.Errorf("photos[0].content_type = %+v, want image/jpeg", created[0].ContentType)
+	}
+	if created[1].ContentType.Valid {
+		t.Errorf("photos[1].content_type = %+v, want null", created[1].ContentType)
+	}
+
+	got, err := p.GetAlbum(ctx, "", album.ID)
+	if err != nil {
+		t.Fatalf("getting album: %v", err)
+	}
+	if got.PhotoCount != 2 {
+		t.Errorf("photo_count = %d, want 2", got.PhotoCount)
+	}
+
+	if _, err := p.CreatePhotos(ctx, "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", photos); !errors.Is(err, cl.ErrNotFound) {
+		t.Errorf("creating photos in a missing album returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestAlbumOrgScoping(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	create := func(orgID, title string) cl.Album {
+		t.Helper()
+		album, err := p.CreateAlbum(ctx, cl.CreateAlbumRequest{Title: title, OrgID: orgID})
+		if err != nil {
+			t.Fatalf("creating album %q: %v", title, err)
+		}
+		t.Cleanup(func() {
+			p.sqlx.MustExec("DELETE FROM albums WHERE id = $1", album.ID)
+		})
+		return album
+	}
+
+	aAlbum := create("org-a", "org scoping a")
+	bAlbum := create("org-b", "org scoping b")
+	if !aAlbum.OrgID.Valid || aAlbum.OrgID.String != "org-a" {
+		t.Fatalf("created album org id = %+v, want org-a", aAlbum.OrgID)
+	}
+
+	albums, err := p.ListAlbums(ctx, cl.ListAlbumsReq{OrgID: "org-a", Limit: 10, Query: "org scoping"})
+	if err != nil {
+		t.Fatalf("listing albums: %v", err)
+	}
+	if len(albums) != 1 || albums[0].ID != aAlbum.ID {
+		t.Errorf("org-a sees %+v, want only its own album", albums)
+	}
+
+	if _, err := p.GetAlbum(ctx, "org-a", aAlbum.ID); err != nil {
+		t.Errorf("getting own album: %v", err)
+	}
+	if _, err := p.GetAlbum(ctx, "org-b", aAlbum.ID); !errors.Is(err, cl.ErrNotFound) {
+		t.Errorf("cross-tenant get returned %v, want ErrNotFound", err)
+	}
+	if _, err := p.GetAlbum(ctx, "", aAlbum.ID); !errors.Is(err, cl.ErrNotFound) {
+		t.Errorf("get without an organization returned %v, want ErrNotFound", err)
+	}
+	if _, err := p.GetAlbum(ctx, "org-b", bAlbum.ID); err != nil {
+		t.Errorf("org-b getting its own album: %v", err)
+	}
+}
+
+func TestCountAlbums(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	early := createTestAlbum(t, p, "count me early")
+	createTestAlbum(t, p, "count me late")
+	p.sqlx.MustExec("UPDATE albums SET created_at = '2020-01-01T00:00:00Z' WHERE id = $1", early.ID)
+
+	count, err := p.CountAlbums(ctx, cl.CountAlbumsReq{Query: "count me"})
+	if err != nil {
+		t.Fatalf("counting albums: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	count, err = p.CountAlbums(ctx, cl.CountAlbumsReq{
+		Query:     "count me",
+		StartDate: null.TimeFrom(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatalf("counting albums with a start date: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count after 2021 = %d, want 1", count)
+	}
+
+	count, err = p.CountAlbums(ctx, cl.CountAlbumsReq{Query: "count me", OrgID: "org-count"})
+	if err != nil {
+		t.Fatalf("counting albums for an org: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("count for an empty org = %d, want 0", count)
+	}
+}
+
+func TestGetAlbumSingleflight(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	db := openTestDB(t,
+		postgres.WithOnComplete(func(_ context.Context, label string, _ time.Duration, err error) error {
+			// Holding the first get_album open here keeps its singleflight
+			// call in flight until every goroutine below has joined it.
+			if label == "get_album" {
+				atomic.AddInt32(&hits, 1)
+				<-release
+			}
+			return err
+		}),
+	)
+	p := &testStore{Postgres: New(db, WithSingleflight()), sqlx: sqlx.NewDb(db.DB(), "postgres")}
+
+	album := createTestAlbum(t, p, "hot album")
+
+	const n = 8
+	var launched, done sync.WaitGroup
+	launched.Add(n)
+	done.Add(n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			launched.Done()
+			got, err := p.GetAlbum(context.Background(), "", album.ID)
+			if err == nil && got.ID != album.ID {
+				err = errors.Errorf("got album %q, want %q", got.ID, album.ID)
+			}
+			errs <- err
+		}()
+	}
+	launched.Wait()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent GetAlbum: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("the database was queried %d times, want 1 shared query", got)
+	}
+}
+
+func TestDeleteAlbums(t *testing.T) {
+	p := newTestPostgres(t)
+
+	first, err := p.CreateAlbum(context.Background(), cl.CreateAlbumRequest{Title: "bulk delete one"})
+	if err != nil {
+		t.Fatalf("creating album: %v", err)
+	}
+	second, err := p.CreateAlbum(context.Background(), cl.CreateAlbumRequest{Title: "bulk delete two"})
+	if err != nil {
+		t.Fatalf("creating album: %v", err)
+	}
+	t.Cleanup(func() {
+		p.sqlx.MustExec("DELETE FROM albums WHERE id IN ($1, $2)", first.ID, second.ID)
+	})
+	if _, err := p.CreatePhotos(context.Background(), first.ID, []cl.Photo{
+		{ID: "9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d", AlbumID: first.ID, Filename: "one.jpg"},
+	}); err != nil {
+		t.Fatalf("creating photo: %v", err)
+	}
+
+	// One id matches nothing; only the two real albums count.
+	deleted, err := p.DeleteAlbums(context.Background(), []string{first.ID, second.ID, "00000000-0000-0000-0000-000000000000"})
+	if err != nil {
+		t.Fatalf("DeleteAlbums: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted = %d, want 2", deleted)
+	}
+
+	if _, err := p.GetAlbum(context.Background(), "", first.ID); !errors.Is(err, cl.ErrNotFound) {
+		t.Errorf("GetAlbum after delete = %v, want ErrNotFound", err)
+	}
+	var photos int
+	if err := p.sqlx.Get(&photos, "SELECT COUNT(*) FROM photos WHERE album_id = $1", first.ID); err != nil {
+		t.Fatalf("counting photos: %v", err)
+	}
+	if photos != 0 {
+		t.Errorf("photos left behind = %d, want the delete cascaded", photos)
+	}
+
+	if deleted, err := p.DeleteAlbums(context.Background(), nil); err != nil || deleted != 0 {
+		t.Errorf("DeleteAlbums(nil) = %d, %v, want 0, nil", deleted, err)
+	}
+}
+
+func TestReplicaRouting(t *testing.T) {
+	// Both pools point at the one test database; the OnComplete hooks
+	// tell apart which pool served each labelled call.
+	openPool := func(seen map[string]int) *postgres.DB {
+		return openTestDB(t,
+			postgres.WithOnComplete(func(_ context.Context, label string, _ time.Duration, err error) error {
+				seen[label]++
+				return err
+			}),
+		)
+	}
+
+	primarySeen, replicaSeen := map[string]int{}, map[string]int{}
+	primary := openPool(primarySeen)
+	replica := openPool(replicaSeen)
+	p := New(primary, WithReplica(replica))
+
+	album, err := p.CreateAlbum(context.Background(), cl.CreateAlbumRequest{Title: "replica routing"})
+	if err != nil {
+		t.Fatalf("creating album: %v", err)
+	}
+	t.Cleanup(func() {
+		primary.DB().Exec("DELETE FROM albums WHERE id = $1", album.ID) //nolint:errcheck
+	})
+
+	if _, err := p.ListAlbums(context.Background(), cl.ListAlbumsReq{Limit: 1}); err != nil {
+		t.Fatalf("listing albums: %v", err)
+	}
+	if _, err := p.GetAlbum(context.Background(), "", album.ID); err != nil {
+		t.Fatalf("getting album: %v", err)
+	}
+	if _, err := p.CountAlbums(context.Background(), cl.CountAlbumsReq{}); err != nil {
+		t.Fatalf("counting albums: %v", err)
+	}
+
+	for _, label := range []string{"list_albums", "get_album", "count_albums"} {
+		if replicaSeen[label] != 1 {
+			t.Errorf("replica saw %s %d times, want 1", label, replicaSeen[label])
+		}
+		if primarySeen[label] != 0 {
+			t.Errorf("primary saw %s %d times, want the read on the replica", label, primarySeen[label])
+		}
+	}
+	if primarySeen["create_album"] != 1 || replicaSeen["create_album"] != 0 {
+		t.Errorf("create_album hit primary %d / replica %d times, want the write on the primary",
+			primarySeen["create_album"], replicaSeen["create_album"])
+	}
+}
+
+func TestReplicaFallsBackToPrimary(t *testing.T) {
+	seen := map[string]int{}
+	db := openTestDB(t,
+		postgres.WithOnComplete(func(_ context.Context, label string, _ time.Duration, err error) error {
+			seen[label]++
+			return err
+		}),
+	)
+
+	p := New(db)
+	if _, err := p.ListAlbums(context.Background(), cl.ListAlbumsReq{Limit: 1}); err != nil {
+		t.Fatalf("listing albums: %v", err)
+	}
+	if seen["list_albums"] != 1 {
+		t.Errorf("primary saw list_albums %d times, want the read to fall back to it", seen["list_albums"])
+	}
+}
@@ -0,0 +1,58 @@
+// Package mock provides hand-rolled fakes shared by the test suites.
+package mock
+
+import (
+	"context"
+
+	cl "github.com/devchiran/golang-demo/internal/catelog"
+)
+
+// AlbumStore fakes the album persistence layer. Each method delegates
+// to the corresponding Fn field, so tests configure only what they use.
+type AlbumStore struct {
+	ListAlbumsFn            func(ctx context.Context, req cl.ListAlbumsReq) ([]cl.Album, error)
+	CountAlbumsFn           func(ctx context.Context, req cl.CountAlbumsReq) (int, error)
+	GetAlbumFn              func(ctx context.Context, orgID, id string) (cl.Album, error)
+	CreateAlbumFn           func(ctx context.Context, req cl.CreateAlbumRequest) (cl.Album, error)
+	PatchAlbumFn            func(ctx context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error)
+	SetAlbumCoverFn         func(ctx context.Context, albumID, photoID string) (cl.Album, error)
+	DeleteAlbumsFn          func(ctx context.Context, ids []string) (int, error)
+	SubscribeAlbumChangesFn func(ctx context.Context) (<-chan string, error)
+	CreatePhotosFn          func(ctx context.Context, albumID string, photos []cl.Photo) ([]cl.Photo, error)
+}
+
+func (s *AlbumStore) ListAlbums(ctx context.Context, req cl.ListAlbumsReq) ([]cl.Album, error) {
+	return s.ListAlbumsFn(ctx, req)
+}
+
+func (s *AlbumStore) CountAlbums(ctx context.Context, req cl.CountAlbumsReq) (int, error) {
+	return s.CountAlbumsFn(ctx, req)
+}
+
+func (s *AlbumStore) GetAlbum(ctx context.Context, orgID, id string) (cl.Album, error) {
+	return s.GetAlbumFn(ctx, orgID, id)
+}
+
+func (s *AlbumStore) CreateAlbum(ctx context.Context, req cl.CreateAlbumRequest) (cl.Album, error) {
+	return s.CreateAlbumFn(ctx, req)
+}
+
+func (s *AlbumStore) PatchAlbum(ctx context.Context, id string, req cl.PatchAlbumRequest) (cl.Album, error) {
+	return s.PatchAlbumFn(ctx, id, req)
+}
+
+func (s *AlbumStore) SetAlbumCover(ctx context.Context, albumID, photoID string) (cl.Album, error) {
+	return s.SetAlbumCoverFn(ctx, albumID, photoID)
+}
+
+func (s *AlbumStore) DeleteAlbums(ctx context.Context, ids []string) (int, error) {
+	return s.DeleteAlbumsFn(ctx, ids)
+}
+
+func (s *AlbumStore) SubscribeAlbumChanges(ctx context.Context) (<-chan string, error) {
+	return s.SubscribeAlbumChangesFn(ctx)
+}
+
+func (s *AlbumStore) CreatePhotos(ctx context.Context, albumID string, photos []cl.Photo) ([]cl.Photo, error) {
+	return s.CreatePhotosFn(ctx, albumID, photos)
+}
@@ -0,0 +1,17 @@
+package mock
+
+import "net/http"
+
+// HTTPClient fakes an HTTP client or transport; Do and RoundTrip both
+// delegate to DoFn.
+type HTTPClient struct {
+	DoFn func(req *http.Request) (*http.Response, error)
+}
+
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.DoFn(req)
+}
+
+func (c *HTTPClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.DoFn(req)
+}
@@ -0,0 +1,32 @@
+package mock
+
+import "context"
+
+// Locker fakes the distlock storage backend. Unset Fn fields behave as
+// an always-available lock.
+type Locker struct {
+	LockFn   func(ctx context.Context, lockID, instanceID string, ttlSeconds int) (bool, error)
+	ExtendFn func(ctx context.Context, lockID, instanceID string, ttlSeconds int) (bool, error)
+	UnlockFn func(ctx context.Context, lockID, instanceID string) error
+}
+
+func (l *Locker) Lock(ctx context.Context, lockID, instanceID string, ttlSeconds int) (bool, error) {
+	if l.LockFn == nil {
+		return true, nil
+	}
+	return l.LockFn(ctx, lockID, instanceID, ttlSeconds)
+}
+
+func (l *Locker) Extend(ctx context.Context, lockID, instanceID string, ttlSeconds int) (bool, error) {
+	if l.ExtendFn == nil {
+		return true, nil
+	}
+	return l.ExtendFn(ctx, lockID, instanceID, ttlSeconds)
+}
+
+func (l *Locker) Unlock(ctx context.Context, lockID, instanceID string) error {
+	if l.UnlockFn == nil {
+		return nil
+	}
+	return l.UnlockFn(ctx, lockID, instanceID)
+}
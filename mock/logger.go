@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"context"
+	"sync"
+)
+
+// LogEntry is a single line recorded by Logger.
+type LogEntry struct {
+	Level   string
+	Msg     string
+	KeyVals []interface{}
+	Ctx     context.Context
+}
+
+// Logger is a tools.LoggerCtx that records every logged line.
+type Logger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// Entries returns a copy of the recorded log lines.
+func (l *Logger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+func (l *Logger) log(level, msg string, ctx context.Context, keyVals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{Level: level, Msg: msg, KeyVals: keyVals, Ctx: ctx})
+}
+
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, nil, keysAndValues)
+}
+
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log("warn", msg, nil, keysAndValues)
+}
+
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.log("error", msg, nil, keysAndValues)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, ctx, keysAndValues)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("warn", msg, ctx, keysAndValues)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log("error", msg, ctx, keysAndValues)
+}
@@ -0,0 +1,12 @@
+package mock
+
+import "context"
+
+// PhotoStorage fakes the presigned upload storage backend.
+type PhotoStorage struct {
+	PresignFn func(ctx context.Context, object, contentType string) (string, error)
+}
+
+func (s *PhotoStorage) Presign(ctx context.Context, object, contentType string) (string, error) {
+	return s.PresignFn(ctx, object, contentType)
+}
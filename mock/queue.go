@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools/queue"
+)
+
+// Queue fakes the queue backend. Unset Fn fields behave as benign
+// no-ops.
+type Queue struct {
+	GetMessagesFn func(ctx context.Context, req queue.GetMessagesRequest) ([]queue.Message, error)
+	AckFn         func(ctx context.Context, m queue.Message) error
+	ExtendFn      func(ctx context.Context, m queue.Message, d time.Duration) error
+}
+
+func (q *Queue) GetMessages(ctx context.Context, req queue.GetMessagesRequest) ([]queue.Message, error) {
+	if q.GetMessagesFn == nil {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return q.GetMessagesFn(ctx, req)
+}
+
+func (q *Queue) Ack(ctx context.Context, m queue.Message) error {
+	if q.AckFn == nil {
+		return nil
+	}
+	return q.AckFn(ctx, m)
+}
+
+func (q *Queue) Extend(ctx context.Context, m queue.Message, d time.Duration) error {
+	if q.ExtendFn == nil {
+		return nil
+	}
+	return q.ExtendFn(ctx, m, d)
+}
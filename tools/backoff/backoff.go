@@ -0,0 +1,60 @@
+// Package backoff implements jittered exponential backoff shared by
+// the retry loops in the tool packages.
+package backoff
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Exponential produces exponentially growing delays with optional
+// jitter, clamped to Max. The zero value produces no delay.
+type Exponential struct {
+	// Base is the delay before the first retry.
+	Base time.Duration
+	// Max clamps the delay. Zero means no clamp.
+	Max time.Duration
+	// Jitter adds up to the given fraction (0..1) of random extra
+	// delay, spreading out competing retriers.
+	Jitter float64
+}
+
+// Delay returns the delay before the given zero-based retry.
+func (e Exponential) Delay(retry int) time.Duration {
+	if e.Base <= 0 {
+		return 0
+	}
+	d := e.Base
+	for i := 0; i < retry; i++ {
+		d *= 2
+		if d <= 0 || (e.Max > 0 && d >= e.Max) {
+			d = e.Max
+			break
+		}
+	}
+	if e.Max > 0 && d > e.Max {
+		d = e.Max
+	}
+	if e.Jitter > 0 {
+		d += time.Duration(rand.Float64() * e.Jitter * float64(d))
+	}
+	return d
+}
+
+// Wait sleeps for the retry's delay or until ctx is done, returning
+// ctx's error in the latter case.
+func (e Exponential) Wait(ctx context.Context, retry int) error {
+	d := e.Delay(retry)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialSequence(t *testing.T) {
+	e := Exponential{Base: time.Second, Max: 10 * time.Second}
+	want := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	for retry, w := range want {
+		if got := e.Delay(retry); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", retry, got, w)
+		}
+	}
+}
+
+func TestExponentialJitterBounds(t *testing.T) {
+	e := Exponential{Base: time.Second, Max: time.Minute, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := e.Delay(2)
+		min, max := 4*time.Second, 6*time.Second
+		if got < min || got > max {
+			t.Fatalf("Delay(2) = %v, want within [%v, %v]", got, min, max)
+		}
+	}
+}
+
+func TestExponentialClampsOverflow(t *testing.T) {
+	e := Exponential{Base: time.Hour, Max: 2 * time.Hour}
+	if got := e.Delay(100); got != 2*time.Hour {
+		t.Errorf("Delay(100) = %v, want %v", got, 2*time.Hour)
+	}
+}
+
+func TestExponentialZeroValue(t *testing.T) {
+	var e Exponential
+	if got := e.Delay(3); got != 0 {
+		t.Errorf("zero-value Delay(3) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,38 @@
+// Package clock abstracts time so components can be tested
+// deterministically.
+package clock
+
+import "time"
+
+// Clock tells the time and schedules callbacks.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// AfterFunc calls f after d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a handle on a pending AfterFunc call.
+type Timer interface {
+	// Stop cancels the call. It reports whether it was still pending.
+	Stop() bool
+}
+
+// New returns the real clock.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
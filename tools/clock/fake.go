@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance is called, for
+// deterministic tests of time-driven behavior.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a Fake starting at the current wall-clock time.
+func NewFake() *Fake {
+	return &Fake{now: time.Now()}
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns how far the fake's current time is past t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// AfterFunc schedules fn to run once Advance has moved the time at
+// least d forward.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, at: f.now.Add(d), fn: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the time forward by d, running due callbacks
+// synchronously in deadline order. Callbacks may schedule further
+// callbacks; those run too if they fall within the advanced window.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+	for {
+		t := f.nextDue()
+		if t == nil {
+			return
+		}
+		t.fn()
+	}
+}
+
+// nextDue pops the earliest pending timer that has reached its
+// deadline, or nil.
+func (f *Fake) nextDue() *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due *fakeTimer
+	for _, t := range f.timers {
+		if t.stopped || t.fired || t.at.After(f.now) {
+			continue
+		}
+		if due == nil || t.at.Before(due.at) {
+			due = t
+		}
+	}
+	if due != nil {
+		due.fired = true
+	}
+	return due
+}
+
+type fakeTimer struct {
+	clock *Fake
+	at    time.Time
+	fn    func()
+	// fired and stopped are guarded by the clock's mutex.
+	fired   bool
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := !t.fired && !t.stopped
+	t.stopped = true
+	return pending
+}
@@ -0,0 +1,196 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func newTestLock(locker *mock.Locker) *DistributedLock {
+	return &DistributedLock{
+		Locker:        locker,
+		LockID:        "job",
+		InstanceID:    "instance-1",
+		TTLSeconds:    60,
+		UnlockTimeout: time.Second,
+	}
+}
+
+func TestDoRetriesAcquisition(t *testing.T) {
+	attempts := 0
+	d := newTestLock(&mock.Locker{
+		LockFn: func(context.Context, string, string, int) (bool, error) {
+			attempts++
+			return attempts >= 2, nil
+		},
+	})
+	d.AcquireRetries = 3
+	d.AcquireBackoff = time.Millisecond
+
+	ran := false
+	ok, err := d.Do(context.Background(), func(context.Context) error {
+		ran = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if !ok || !ran {
+		t.Errorf("ok = %v, ran = %v, want the lock acquired on the second attempt", ok, ran)
+	}
+	if attempts != 2 {
+		t.Errorf("Lock called %d times, want 2", attempts)
+	}
+}
+
+func TestDoDefaultsToSingleAttempt(t *testing.T) {
+	attempts := 0
+	d := newTestLock(&mock.Locker{
+		LockFn: func(context.Context, string, string, int) (bool, error) {
+			attempts++
+			return false, nil
+		},
+	})
+
+	ok, err := d.Do(context.Background(), func(context.Context) error {
+		t.Error("fn ran without the lock")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if ok {
+		t.Error("expected ok = false when the lock is held elsewhere")
+	}
+	if attempts != 1 {
+		t.Errorf("Lock called %d times, want 1", attempts)
+	}
+}
+
+func TestDoAcquisitionStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := newTestLock(&mock.Locker{
+		LockFn: func(context.Context, string, string, int) (bool, error) {
+			cancel()
+			return false, nil
+		},
+	})
+	d.AcquireRetries = 100
+	d.AcquireBackoff = time.Hour
+
+	if _, err := d.Do(ctx, func(context.Context) error { return nil }); err == nil {
+		t.Error("expected a context error")
+	}
+}
+
+func TestUnlockRetriesOnceWithinBudget(t *testing.T) {
+	unlocks := 0
+	d := newTestLock(&mock.Locker{
+		UnlockFn: func(context.Context, string, string) error {
+			unlocks++
+			if unlocks == 1 {
+				return errors.New("transient blip")
+			}
+			return nil
+		},
+	})
+	d.MaxRetries = 3
+	d.RetryBaseDuration = time.Millisecond
+
+	var reported []error
+	d.ErrorFunc = func(err error) { reported = append(reported, err) }
+
+	start := time.Now()
+	ok, err := d.Do(context.Background(), func(context.Context) error { return nil })
+	if err != nil || !ok {
+		t.Fatalf("Do = %v, %v", ok, err)
+	}
+
+	if unlocks != 2 {
+		t.Errorf("Unlock called %d times, want the failure retried once", unlocks)
+	}
+	if len(reported) != 1 {
+		t.Errorf("ErrorFunc saw %d errors, want the single failed attempt", len(reported))
+	}
+	if took := time.Since(start); took > d.UnlockTimeout {
+		t.Errorf("unlock took %v, want it within the %v budget", took, d.UnlockTimeout)
+	}
+}
+
+func TestNewDistributedLockDefaults(t *testing.T) {
+	d, err := NewDistributedLock(&mock.Locker{}, "job", "instance-1")
+	if err != nil {
+		t.Fatalf("NewDistributedLock: %v", err)
+	}
+	if d.TTLSeconds != defaultTTLSeconds {
+		t.Errorf("TTLSeconds = %d, want %d", d.TTLSeconds, defaultTTLSeconds)
+	}
+	if d.MaxRetries != defaultMaxRetries {
+		t.Errorf("MaxRetries = %d, want %d", d.MaxRetries, defaultMaxRetries)
+	}
+	if d.RetryBaseDuration != defaultRetryBaseDuration {
+		t.Errorf("RetryBaseDuration = %v, want %v", d.RetryBaseDuration, defaultRetryBaseDuration)
+	}
+	if d.UnlockTimeout != defaultUnlockTimeout {
+		t.Errorf("UnlockTimeout = %v, want %v", d.UnlockTimeout, defaultUnlockTimeout)
+	}
+}
+
+func TestNewDistributedLockOptions(t *testing.T) {
+	var seen []error
+	d, err := NewDistributedLock(&mock.Locker{}, "job", "instance-1",
+		WithTTLSeconds(10),
+		WithRetry(1, time.Millisecond),
+		WithAcquireRetries(2, time.Millisecond),
+		WithUnlockTimeout(time.Second),
+		WithErrorFunc(func(err error) { seen = append(seen, err) }),
+	)
+	if err != nil {
+		t.Fatalf("NewDistributedLock: %v", err)
+	}
+	if d.TTLSeconds != 10 || d.MaxRetries != 1 || d.RetryBaseDuration != time.Millisecond {
+		t.Errorf("options not applied: %+v", d)
+	}
+	if d.AcquireRetries != 2 || d.UnlockTimeout != time.Second || d.ErrorFunc == nil {
+		t.Errorf("options not applied: %+v", d)
+	}
+	d.ErrorFunc(errors.New("boom"))
+	if len(seen) != 1 {
+		t.Errorf("ErrorFunc saw %d errors, want 1", len(seen))
+	}
+}
+
+func TestNewDistributedLockRejectsInvalidTTL(t *testing.T) {
+	for _, ttl := range []int{0, -5} {
+		if _, err := NewDistributedLock(&mock.Locker{}, "job", "instance-1", WithTTLSeconds(ttl)); err == nil {
+			t.Errorf("TTL %d accepted, want an error", ttl)
+		}
+	}
+}
+
+func TestUnlockStopsAtTimeout(t *testing.T) {
+	unlocks := 0
+	d := newTestLock(&mock.Locker{
+		UnlockFn: func(context.Context, string, string) error {
+			unlocks++
+			return errors.New("backend down")
+		},
+	})
+	d.UnlockTimeout = 20 * time.Millisecond
+	d.MaxRetries = 100
+	d.RetryBaseDuration = 10 * time.Millisecond
+
+	start := time.Now()
+	if _, err := d.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("unlock blocked for %v, want it cut off near the 20ms budget", took)
+	}
+	if unlocks == 0 {
+		t.Error("expected at least one unlock attempt")
+	}
+}
@@ -0,0 +1,28 @@
+package httputils
+
+import (
+	"encoding/csv"
+	"net/http"
+)
+
+const contentTypeCSV = "text/csv; charset=utf-8"
+
+// WriteCSV streams a CSV attachment: the header row first, then
+// whatever records write produces. Quoting and escaping are left to
+// encoding/csv. Errors are returned rather than written, since the
+// status code is already on the wire.
+func WriteCSV(w http.ResponseWriter, filename string, header []string, write func(*csv.Writer) error) error {
+	w.Header().Set("Content-Type", contentTypeCSV)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := write(cw); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
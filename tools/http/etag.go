@@ -0,0 +1,32 @@
+package httputils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// WeakETag returns a weak entity tag derived deterministically from the
+// given parts.
+func WeakETag(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return `W/"` + hex.EncodeToString(h[:8]) + `"`
+}
+
+// IfNoneMatch reports whether the request's If-None-Match header
+// matches etag, in which case the caller should respond 304 Not
+// Modified.
+func IfNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
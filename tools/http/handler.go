@@ -0,0 +1,55 @@
+package httputils
+
+import (
+	"net/http"
+
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// NotFoundHandler writes the standard JSON 404 response.
+func NotFoundHandler(logger tools.WarnLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Warn("route not found", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "not found", http.StatusNotFound)
+	}
+}
+
+// MethodNotAllowedHandler writes the standard JSON 405 response.
+func MethodNotAllowedHandler(logger tools.WarnLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Warn("method not allowed", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// InternalServerErrorHandler writes the standard JSON 500 response.
+func InternalServerErrorHandler(logger tools.ErrorLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Error("internal server error", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ServiceUnavailableHandler writes the standard JSON 503 response.
+func ServiceUnavailableHandler(logger tools.WarnLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Warn("service unavailable", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "service unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+// GatewayTimeoutHandler writes the standard JSON 504 response.
+func GatewayTimeoutHandler(logger tools.WarnLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Warn("gateway timeout", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "gateway timeout", http.StatusGatewayTimeout)
+	}
+}
+
+// TooManyRequestsHandler writes the standard JSON 429 response.
+func TooManyRequestsHandler(logger tools.WarnLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Warn("too many requests", "uri", r.RequestURI, "method", r.Method)
+		WriteJSONError(w, r.URL.Query(), "too many requests", http.StatusTooManyRequests)
+	}
+}
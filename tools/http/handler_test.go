@@ -0,0 +1,43 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func TestErrorHandlers(t *testing.T) {
+	logger := &mock.Logger{}
+	for _, tc := range []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "gateway timeout", handler: GatewayTimeoutHandler(logger), wantCode: http.StatusGatewayTimeout, wantMessage: "gateway timeout"},
+		{name: "too many requests", handler: TooManyRequestsHandler(logger), wantCode: http.StatusTooManyRequests, wantMessage: "too many requests"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d", tc.wantCode, rec.Code)
+			}
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if body.Error.Message != tc.wantMessage {
+				t.Errorf("message = %q, want %q", body.Error.Message, tc.wantMessage)
+			}
+		})
+	}
+}
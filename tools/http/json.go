@@ -0,0 +1,262 @@
+package httputils
+
+import (
+	"context"
+	gojson "encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/devchiran/golang-demo/tools/json"
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+const contentTypeJSON = "application/json; charset=utf-8"
+
+// JSONErr is the standard error envelope written by the error helpers.
+// RequestID echoes the request's id so clients can quote it when
+// reporting a failure; the Ctx variants populate it from the context.
+type JSONErr struct {
+	Error     JSONErrDetail `json:"error"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// JSONErrDetail carries the human-readable message and, optionally, a
+// stable machine-readable type and a per-field breakdown.
+type JSONErrDetail struct {
+	Message string           `json:"message"`
+	Type    string           `json:"type,omitempty"`
+	Fields  []JSONFieldError `json:"fields,omitempty"`
+}
+
+// JSONFieldError pinpoints one invalid request field in the error
+// envelope.
+type JSONFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// JSONData is the {"data": ...} envelope used by informational endpoints.
+type JSONData struct {
+	Data interface{} `json:"data"`
+}
+
+// indentStrings is the allowlist of values for the indent query
+// parameter; anything else falls back to the two-space default.
+var indentStrings = map[string]string{
+	"2":   "  ",
+	"4":   "    ",
+	"tab": "\t",
+}
+
+// indent returns the indent string requested via the query parameters.
+// An empty string means compact output. ?pretty turns indentation on
+// (unless given a false-y value) and ?indent=2|4|tab picks the string,
+// defaulting to two spaces.
+func indent(v url.Values) string {
+	if v == nil {
+		return ""
+	}
+	vals, ok := v["pretty"]
+	if !ok {
+		return ""
+	}
+	if len(vals) > 0 && vals[0] != "" {
+		if b, err := strconv.ParseBool(vals[0]); err == nil && !b {
+			return ""
+		}
+	}
+	if in, ok := indentStrings[v.Get("indent")]; ok {
+		return in
+	}
+	return "  "
+}
+
+// WriteJSON marshals body and writes it with the given status code. The
+// request's query values control pretty printing via ?pretty and
+// ?indent.
+func WriteJSON(w http.ResponseWriter, v url.Values, body interface{}, code int) {
+	var (
+		b   []byte
+		err error
+	)
+	if in := indent(v); in != "" {
+		b, err = json.MarshalIndent(body, "", in)
+	} else {
+		b, err = json.Marshal(body)
+	}
+	if err != nil {
+		http.Error(w, `{"error":{"message":"internal server error"}}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	w.Write(b) //nolint:errcheck
+}
+
+// WriteRawJSON writes pre-serialized JSON as-is, for cached responses
+// that would otherwise be marshaled on every request. The body is
+// trusted to be valid JSON; use WriteRawJSONChecked when it is not.
+func WriteRawJSON(w http.ResponseWriter, body []byte, code int) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	w.Write(body) //nolint:errcheck
+}
+
+// WriteRawJSONChecked is WriteRawJSON for bodies from less trusted
+// sources: it refuses to write anything that is not valid JSON, so a
+// corrupt cache entry surfaces as an error instead of a broken
+// response.
+func WriteRawJSONChecked(w http.ResponseWriter, body []byte, code int) error {
+	if !gojson.Valid(body) {
+		return errors.New("httputils: body is not valid JSON")
+	}
+	WriteRawJSON(w, body, code)
+	return nil
+}
+
+// WriteJSONData wraps body in the standard {"data": ...} envelope.
+func WriteJSONData(w http.ResponseWriter, v url.Values, body interface{}, code int) {
+	WriteJSON(w, v, JSONData{Data: body}, code)
+}
+
+// WriteJSONError writes the standard error envelope with the given
+// message.
+func WriteJSONError(w http.ResponseWriter, v url.Values, msg string, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg}}, code)
+}
+
+// WriteJSONErrorWithType writes the standard error envelope with a
+// stable machine-readable type (e.g. not_found, validation_error)
+// alongside the human-readable message.
+func WriteJSONErrorWithType(w http.ResponseWriter, v url.Values, errType, msg string, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg, Type: errType}}, code)
+}
+
+// WriteJSONErrorFields is WriteJSONErrorWithType with a per-field
+// breakdown of what was invalid.
+func WriteJSONErrorFields(w http.ResponseWriter, v url.Values, errType, msg string, fields []JSONFieldError, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg, Type: errType, Fields: fields}}, code)
+}
+
+// WriteJSONErrorCtx is WriteJSONError with the request id from ctx
+// echoed in the envelope, when one is set.
+func WriteJSONErrorCtx(ctx context.Context, w http.ResponseWriter, v url.Values, msg string, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg}, RequestID: requestid.Get(ctx)}, code)
+}
+
+// WriteJSONErrorWithTypeCtx is WriteJSONErrorWithType with the request
+// id from ctx echoed in the envelope, when one is set.
+func WriteJSONErrorWithTypeCtx(ctx context.Context, w http.ResponseWriter, v url.Values, errType, msg string, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg, Type: errType}, RequestID: requestid.Get(ctx)}, code)
+}
+
+// WriteJSONErrorFieldsCtx is WriteJSONErrorFields with the request id
+// from ctx echoed in the envelope, when one is set.
+func WriteJSONErrorFieldsCtx(ctx context.Context, w http.ResponseWriter, v url.Values, errType, msg string, fields []JSONFieldError, code int) {
+	WriteJSON(w, v, JSONErr{Error: JSONErrDetail{Message: msg, Type: errType, Fields: fields}, RequestID: requestid.Get(ctx)}, code)
+}
+
+// streamFlushEvery is how many array elements WriteJSONStream writes
+// between flushes.
+const streamFlushEvery = 64
+
+// WriteJSONStream writes an object of the form
+//
+//	{"header": <header>, "items": [<item>, ...]}
+//
+// streaming the items from the channel instead of buffering the whole
+// payload, and flushing periodically. The header field is omitted when
+// header is nil. Like WriteJSON it honors ?pretty and sets the content
+// type; unlike WriteJSON it returns marshalling and write errors, since
+// by then the status code is already on the wire.
+func WriteJSONStream(w http.ResponseWriter, v url.Values, header interface{}, items <-chan interface{}, code int) error {
+	in := indent(v)
+
+	var head, itemPrefix, itemSep, tail string
+	marshalItem := json.Marshal
+	if in == "" {
+		head, itemSep, tail = `{"items":[`, ",", "]}"
+		if header != nil {
+			hb, err := json.Marshal(header)
+			if err != nil {
+				return err
+			}
+			head = `{"header":` + string(hb) + `,"items":[`
+		}
+	} else {
+		head = "{\n" + in + "\"items\": [\n"
+		if header != nil {
+			hb, err := json.MarshalIndent(header, in, in)
+			if err != nil {
+				return err
+			}
+			head = "{\n" + in + "\"header\": " + string(hb) + ",\n" + in + "\"items\": [\n"
+		}
+		itemPrefix, itemSep, tail = in+in, ",\n", "\n"+in+"]\n}"
+		marshalItem = func(item interface{}) ([]byte, error) {
+			return json.MarshalIndent(item, in+in, in)
+		}
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	flusher, _ := w.(http.Flusher)
+	if _, err := io.WriteString(w, head); err != nil {
+		return err
+	}
+
+	first := true
+	written := 0
+	for item := range items {
+		b, err := marshalItem(item)
+		if err != nil {
+			return err
+		}
+		sep := itemSep
+		if first {
+			sep, first = "", false
+		}
+		if _, err := io.WriteString(w, sep+itemPrefix); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if written++; flusher != nil && written%streamFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+
+	_, err := io.WriteString(w, tail)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return err
+}
+
+// ReadJSON decodes a JSON request body into v.
+func ReadJSON(r io.Reader, v interface{}) error {
+	return json.Decode(r, v)
+}
+
+// ErrBodyTooLarge is returned by ReadJSONLimited when the body exceeds
+// the caller's limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// ReadJSONLimited decodes like ReadJSON but reads at most maxBytes
+// bytes, returning ErrBodyTooLarge beyond that. It guards individual
+// handlers independently of LimitReaderMiddleware, whose limit covers
+// the whole stack.
+func ReadJSONLimited(r io.Reader, v interface{}, maxBytes int64) error {
+	// One extra byte distinguishes "exactly at the limit" from "over
+	// it": only an oversized body drains the reader past maxBytes.
+	limited := &io.LimitedReader{R: r, N: maxBytes + 1}
+	err := json.Decode(limited, v)
+	if limited.N <= 0 {
+		return ErrBodyTooLarge
+	}
+	return err
+}
@@ -0,0 +1,281 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+func TestWriteJSONErrorWithType(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteJSONErrorWithType(rec, nil, "not_found", "album not found", http.StatusNotFound)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	var body JSONErr
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.Error.Type != "not_found" {
+		t.Errorf("type = %q, want not_found", body.Error.Type)
+	}
+	if body.Error.Message != "album not found" {
+		t.Errorf("message = %q, want album not found", body.Error.Message)
+	}
+}
+
+// countingRecorder counts Write calls to show output is not buffered
+// into a single write.
+type countingRecorder struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (c *countingRecorder) Write(b []byte) (int, error) {
+	c.writes++
+	return c.ResponseRecorder.Write(b)
+}
+
+func TestWriteRawJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteRawJSON(rec, []byte(`{"cached":true}`), http.StatusOK)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != contentTypeJSON {
+		t.Errorf("content type = %q, want %q", ct, contentTypeJSON)
+	}
+	if rec.Body.String() != `{"cached":true}` {
+		t.Errorf("body = %q, want the bytes untouched", rec.Body.String())
+	}
+}
+
+func TestWriteRawJSONChecked(t *testing.T) {
+	t.Run("valid body passes through", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if err := WriteRawJSONChecked(rec, []byte(`[1,2,3]`), http.StatusCreated); err != nil {
+			t.Fatalf("WriteRawJSONChecked: %v", err)
+		}
+		if rec.Code != http.StatusCreated || rec.Body.String() != `[1,2,3]` {
+			t.Errorf("wrote %d %q, want 201 with the body untouched", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("invalid body writes nothing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if err := WriteRawJSONChecked(rec, []byte(`{"broken":`), http.StatusOK); err == nil {
+			t.Fatal("expected an error for invalid JSON")
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("expected nothing written, got %q", rec.Body.String())
+		}
+	})
+}
+
+func TestWriteJSONStream(t *testing.T) {
+	const n = 500
+	items := make(chan interface{})
+	go func() {
+		for i := 0; i < n; i++ {
+			items <- i
+		}
+		close(items)
+	}()
+
+	rec := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	header := map[string]int{"count": n}
+	if err := WriteJSONStream(rec, nil, header, items, http.StatusOK); err != nil {
+		t.Fatalf("streaming: %v", err)
+	}
+
+	var body struct {
+		Header map[string]int `json:"header"`
+		Items  []int          `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, rec.Body.String())
+	}
+	if body.Header["count"] != n {
+		t.Errorf("header count = %d, want %d", body.Header["count"], n)
+	}
+	if len(body.Items) != n {
+		t.Fatalf("got %d items, want %d", len(body.Items), n)
+	}
+	for i, v := range body.Items {
+		if v != i {
+			t.Fatalf("items[%d] = %d, want %d", i, v, i)
+		}
+	}
+	if rec.writes < n {
+		t.Errorf("payload written in %d writes; expected per-item writes, not wholesale buffering", rec.writes)
+	}
+}
+
+func TestWriteJSONStreamPretty(t *testing.T) {
+	items := make(chan interface{}, 2)
+	items <- "a"
+	items <- "b"
+	close(items)
+
+	rec := httptest.NewRecorder()
+	v := map[string][]string{"pretty": {"true"}}
+	if err := WriteJSONStream(rec, v, nil, items, http.StatusOK); err != nil {
+		t.Fatalf("streaming: %v", err)
+	}
+
+	var body struct {
+		Items []string `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, rec.Body.String())
+	}
+	if len(body.Items) != 2 || body.Items[0] != "a" || body.Items[1] != "b" {
+		t.Errorf("items = %v, want [a b]", body.Items)
+	}
+	if !strings.Contains(rec.Body.String(), "\n") {
+		t.Error("expected indented output for ?pretty")
+	}
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "no query means compact", query: "", want: ""},
+		{name: "pretty defaults to two spaces", query: "pretty", want: "  "},
+		{name: "pretty=false stays compact", query: "pretty=false", want: ""},
+		{name: "indent alone stays compact", query: "indent=4", want: ""},
+		{name: "two spaces", query: "pretty&indent=2", want: "  "},
+		{name: "four spaces", query: "pretty&indent=4", want: "    "},
+		{name: "tab", query: "pretty&indent=tab", want: "\t"},
+		{name: "unknown indent falls back", query: "pretty&indent=8", want: "  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parsing query: %v", err)
+			}
+			if got := indent(v); got != tt.want {
+				t.Errorf("indent(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSONIndentOptions(t *testing.T) {
+	body := map[string]string{"k": "v"}
+
+	t.Run("tab indent", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		v := url.Values{"pretty": {""}, "indent": {"tab"}}
+		WriteJSON(rec, v, body, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), "\n\t") {
+			t.Errorf("expected tab-indented output, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("compact by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteJSON(rec, nil, body, http.StatusOK)
+		if strings.Contains(rec.Body.String(), "\n") {
+			t.Errorf("expected compact output, got %q", rec.Body.String())
+		}
+	})
+}
+
+func TestReadJSONLimited(t *testing.T) {
+	body := `{"title":"holiday"}`
+
+	t.Run("at the limit", func(t *testing.T) {
+		var v struct {
+			Title string `json:"title"`
+		}
+		if err := ReadJSONLimited(strings.NewReader(body), &v, int64(len(body))); err != nil {
+			t.Fatalf("ReadJSONLimited: %v", err)
+		}
+		if v.Title != "holiday" {
+			t.Errorf("title = %q, want holiday", v.Title)
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		var v struct {
+			Title string `json:"title"`
+		}
+		err := ReadJSONLimited(strings.NewReader(body), &v, int64(len(body))-1)
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("invalid JSON within the limit", func(t *testing.T) {
+		var v interface{}
+		err := ReadJSONLimited(strings.NewReader(`{"broken":`), &v, 1024)
+		if err == nil || errors.Is(err, ErrBodyTooLarge) {
+			t.Fatalf("expected a decode error, got %v", err)
+		}
+	})
+}
+
+func TestWriteJSONErrorOmitsEmptyType(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteJSONError(rec, nil, "boom", http.StatusInternalServerError)
+
+	if strings.Contains(rec.Body.String(), `"type"`) {
+		t.Errorf("expected the type field to be omitted, got %s", rec.Body.String())
+	}
+}
+
+func TestWriteJSONErrorCtx(t *testing.T) {
+	t.Run("echoes the request id from context", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctx := requestid.Set(context.Background(), "req-42")
+		WriteJSONErrorCtx(ctx, rec, nil, "boom", http.StatusInternalServerError)
+
+		var body JSONErr
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if body.RequestID != "req-42" {
+			t.Errorf("request_id = %q, want req-42", body.RequestID)
+		}
+		if body.Error.Message != "boom" {
+			t.Errorf("message = %q, want boom", body.Error.Message)
+		}
+	})
+
+	t.Run("omits the field without a request id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteJSONErrorCtx(context.Background(), rec, nil, "boom", http.StatusInternalServerError)
+
+		if strings.Contains(rec.Body.String(), `"request_id"`) {
+			t.Errorf("expected request_id to be omitted, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestWriteJSONErrorWithTypeCtx(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := requestid.Set(context.Background(), "req-7")
+	WriteJSONErrorWithTypeCtx(ctx, rec, nil, "not_found", "album not found", http.StatusNotFound)
+
+	var body JSONErr
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.RequestID != "req-7" || body.Error.Type != "not_found" {
+		t.Errorf("body = %+v, want the request id and type populated", body)
+	}
+}
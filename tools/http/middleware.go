@@ -0,0 +1,492 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/devchiran/golang-demo/tools"
+	"github.com/devchiran/golang-demo/tools/clock"
+	"github.com/devchiran/golang-demo/tools/requestid"
+	"github.com/devchiran/golang-demo/tools/semaphore"
+)
+
+// responseWriter records the status code and bytes written by a handler.
+type responseWriter struct {
+	http.ResponseWriter
+	Code  int
+	Bytes int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.Code == 0 {
+		w.Code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.Code == 0 {
+		w.Code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// RequestIDMiddleware assigns each request an id, stored on the context
+// and echoed in the X-Request-Id header. An id supplied by the caller is
+// kept.
+func RequestIDMiddleware(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if id == "" {
+			id = uuid.Must(uuid.NewV4()).String()
+		}
+		w.Header().Set("X-Request-Id", id)
+		fn(w, r.WithContext(requestid.Set(r.Context(), id)))
+	}
+}
+
+// LoggingMiddleware logs one line per handled request.
+func LoggingMiddleware(logger tools.InfoLogger, fn http.HandlerFunc) http.HandlerFunc {
+	return LoggingMiddlewareWithClock(logger, clock.New(), fn)
+}
+
+// LoggingMiddlewareWithClock is LoggingMiddleware with an injectable
+// clock, so tests can assert the logged duration without real sleeps.
+func LoggingMiddlewareWithClock(logger tools.InfoLogger, cl clock.Clock, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := cl.Now()
+		wr := &responseWriter{ResponseWriter: w}
+		fn(wr, r)
+		logger.Info("request handled",
+			"uri", r.RequestURI,
+			"method", r.Method,
+			"source", r.RemoteAddr,
+			"code", wr.Code,
+			"duration", cl.Since(start).String(),
+			"request_id", requestid.Get(r.Context()),
+		)
+	}
+}
+
+// AccessLogMiddleware logs one line per handled request under a fixed
+// field schema, and records duration and response-size metrics. Unlike
+// LoggingMiddleware it logs with the request context, so fields derived
+// from it (request id, trace id) attach to the line.
+func AccessLogMiddleware(logger tools.InfoLoggerCtx, sc tools.StatsClient, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wr := &responseWriter{ResponseWriter: w}
+		fn(wr, r)
+		took := time.Since(start)
+
+		route := r.URL.Path
+		if cur := mux.CurrentRoute(r); cur != nil && cur.GetName() != "" {
+			route = cur.GetName()
+		}
+		peer := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			peer = host
+		}
+		logger.InfoCtx(r.Context(), "request handled",
+			"http.method", r.Method,
+			"http.status", wr.Code,
+			"http.route", route,
+			"http.duration_ms", float64(took.Microseconds())/1000,
+			"http.bytes", wr.Bytes,
+			"net.peer.ip", peer,
+		)
+		if sc != nil {
+			sc.Histogram("http.duration_ms", float64(took.Microseconds())/1000, "method:"+r.Method)
+			sc.Histogram("http.bytes", float64(wr.Bytes), "method:"+r.Method)
+		}
+	}
+}
+
+// RecoverMiddleware converts handler panics into 500 responses. If the
+// handler already wrote a header, the status cannot be rewritten; the
+// middleware then re-panics with http.ErrAbortHandler so the server
+// aborts the connection and the client does not mistake the truncated
+// body for a complete response.
+func RecoverMiddleware(logger tools.ErrorLogger, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wr, ok := w.(*responseWriter)
+		if !ok {
+			wr = &responseWriter{ResponseWriter: w}
+		}
+		defer func() {
+			if rec := recover(); rec != nil {
+				logger.Error("panic while handling request",
+					"uri", r.RequestURI,
+					"panic", fmt.Sprintf("%v", rec),
+					"stack", string(debug.Stack()),
+				)
+				if wr.Code == 0 {
+					WriteJSONError(wr, r.URL.Query(), "internal server error", http.StatusInternalServerError)
+					return
+				}
+				panic(http.ErrAbortHandler)
+			}
+		}()
+		fn(wr, r)
+	}
+}
+
+// RecoverMiddlewareCtx is RecoverMiddleware with a context-aware
+// logger, so fields derived from the request context (request id, trace
+// id) make it onto the panic log line. Partially written responses are
+// aborted the same way.
+func RecoverMiddlewareCtx(logger tools.ErrorLoggerCtx, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wr, ok := w.(*responseWriter)
+		if !ok {
+			wr = &responseWriter{ResponseWriter: w}
+		}
+		defer func() {
+			if rec := recover(); rec != nil {
+				logger.ErrorCtx(r.Context(), "panic while handling request",
+					"uri", r.RequestURI,
+					"panic", fmt.Sprintf("%v", rec),
+					"stack", string(debug.Stack()),
+				)
+				if wr.Code == 0 {
+					WriteJSONError(wr, r.URL.Query(), "internal server error", http.StatusInternalServerError)
+					return
+				}
+				panic(http.ErrAbortHandler)
+			}
+		}()
+		fn(wr, r)
+	}
+}
+
+// StatsMiddleware records request count and duration under name. A nil
+// client disables recording rather than panicking.
+func StatsMiddleware(sc tools.StatsClient, name string, fn http.HandlerFunc) http.HandlerFunc {
+	return StatsMiddlewareWithClock(sc, name, clock.New(), fn)
+}
+
+// StatsMiddlewareWithClock is StatsMiddleware with an injectable clock,
+// giving tests control over the recorded duration.
+func StatsMiddlewareWithClock(sc tools.StatsClient, name string, cl clock.Clock, fn http.HandlerFunc) http.HandlerFunc {
+	sc = tools.OrNop(sc)
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := cl.Now()
+		wr := &responseWriter{ResponseWriter: w}
+		fn(wr, r)
+		sc.Count(name+".requests", 1, "code:"+strconv.Itoa(wr.Code), "method:"+r.Method)
+		sc.Histogram(name+".duration_seconds", cl.Since(start).Seconds(), "method:"+r.Method)
+	}
+}
+
+// inFlightSampleInterval is how often InFlightMiddleware reports the
+// age of the oldest in-flight request.
+const inFlightSampleInterval = 10 * time.Second
+
+// inFlightTracker remembers when each in-flight request started.
+type inFlightTracker struct {
+	mu     sync.Mutex
+	nextID uint64
+	starts map[uint64]time.Time
+}
+
+func (t *inFlightTracker) add() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.starts[t.nextID] = time.Now()
+	return t.nextID
+}
+
+func (t *inFlightTracker) remove(id uint64) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.starts, id)
+	return len(t.starts)
+}
+
+func (t *inFlightTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.starts)
+}
+
+// oldestAge returns how long the oldest in-flight request has been
+// running, or zero when none are.
+func (t *inFlightTracker) oldestAge() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var oldest time.Time
+	for _, start := range t.starts {
+		if oldest.IsZero() || start.Before(oldest) {
+			oldest = start
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// InFlightMiddleware gauges how many requests are being handled
+// concurrently and, every sampling interval, the age of the oldest one.
+// The decrement is deferred so panicking handlers are still counted
+// out. The sampler goroutine lives as long as the process, which is the
+// lifetime of the router it is wired into.
+func InFlightMiddleware(sc tools.StatsClient, name string, fn http.HandlerFunc) http.HandlerFunc {
+	sc = tools.OrNop(sc)
+	t := &inFlightTracker{starts: map[uint64]time.Time{}}
+	go func() {
+		for range time.Tick(inFlightSampleInterval) {
+			sc.Gauge(name+".oldest_age_seconds", t.oldestAge().Seconds())
+		}
+	}()
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := t.add()
+		sc.Gauge(name+".in_flight", float64(t.count()))
+		defer func() {
+			sc.Gauge(name+".in_flight", float64(t.remove(id)))
+		}()
+		fn(w, r)
+	}
+}
+
+// bucketRegistrar is implemented by stats clients whose histograms have
+// configurable buckets, such as the Prometheus client in tools/stats.
+// Bucketing necessarily lives in the implementation because
+// tools.StatsClient.Histogram only carries raw observations.
+type bucketRegistrar interface {
+	RegisterBuckets(name string, buckets []float64)
+}
+
+// StatsMiddlewareWithBuckets is StatsMiddleware with custom buckets for
+// the duration histogram. The buckets are registered up front when the
+// client supports it and silently ignored otherwise.
+func StatsMiddlewareWithBuckets(sc tools.StatsClient, name string, buckets []float64, fn http.HandlerFunc) http.HandlerFunc {
+	if br, ok := sc.(bucketRegistrar); ok && len(buckets) > 0 {
+		br.RegisterBuckets(name+".duration_seconds", buckets)
+	}
+	return StatsMiddleware(sc, name, fn)
+}
+
+// RouteLabel returns the metrics label for r: the matched mux route's
+// name when there is one, "method_not_allowed" for 405 responses and
+// "not_found" otherwise. The fallbacks keep unmatched requests from
+// producing blank labels — or an unbounded label set from raw paths.
+func RouteLabel(r *http.Request, status int) string {
+	if cur := mux.CurrentRoute(r); cur != nil {
+		if name := cur.GetName(); name != "" {
+			return name
+		}
+	}
+	if status == http.StatusMethodNotAllowed {
+		return "method_not_allowed"
+	}
+	return "not_found"
+}
+
+// StatsRouteMiddleware records per-route request counts using
+// RouteLabel. Mount it inside the router (mux's Use) so the matched
+// route is visible; wrapped around the router it only ever sees the
+// unmatched fallbacks.
+func StatsRouteMiddleware(sc tools.StatsClient, fn http.HandlerFunc) http.HandlerFunc {
+	sc = tools.OrNop(sc)
+	return func(w http.ResponseWriter, r *http.Request) {
+		wr := &responseWriter{ResponseWriter: w}
+		fn(wr, r)
+		sc.Count("http.requests", 1, "route:"+RouteLabel(r, wr.Code), "code:"+strconv.Itoa(wr.Code))
+	}
+}
+
+// DrainMiddleware rejects new requests with 503 once isDraining reports
+// true, typically wired to the lifecycle context so a shutting-down
+// process stops admitting work it may not finish. Requests already in a
+// handler are unaffected.
+func DrainMiddleware(isDraining func() bool, logger tools.WarnLogger, fn http.HandlerFunc) http.HandlerFunc {
+	unavailable := ServiceUnavailableHandler(logger)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isDraining() {
+			unavailable(w, r)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// TimeoutMiddleware bounds how long a handler may run by deriving a
+// context deadline for the request.
+func TimeoutMiddleware(d time.Duration, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		fn(w, r.WithContext(ctx))
+	}
+}
+
+// TimeoutMiddlewareExcept is TimeoutMiddleware with an escape hatch:
+// requests matching skip run without a deadline. Streaming endpoints
+// (SSE, long polls) opt out this way while every other route keeps the
+// bound.
+func TimeoutMiddlewareExcept(d time.Duration, skip func(*http.Request) bool, fn http.HandlerFunc) http.HandlerFunc {
+	timed := TimeoutMiddleware(d, fn)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if skip != nil && skip(r) {
+			fn(w, r)
+			return
+		}
+		timed(w, r)
+	}
+}
+
+// MaxConnectionsMiddleware rejects requests with 503 once more than max
+// are being handled concurrently.
+func MaxConnectionsMiddleware(max int64, logger tools.WarnLogger, fn http.HandlerFunc) http.HandlerFunc {
+	var inFlight int64
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&inFlight, 1) > max {
+			atomic.AddInt64(&inFlight, -1)
+			logger.Warn("max connections exceeded", "max", max)
+			WriteJSONError(w, r.URL.Query(), "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer atomic.AddInt64(&inFlight, -1)
+		fn(w, r)
+	}
+}
+
+// ConcurrentLimitMiddleware lets up to limit requests run concurrently,
+// queueing a bounded number behind them before rejecting with 503.
+func ConcurrentLimitMiddleware(limit int64, logger tools.WarnLogger, fn http.HandlerFunc) http.HandlerFunc {
+	sem := semaphore.New(limit, limit*4)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := sem.Acquire(r.Context()); err != nil {
+			logger.Warn("concurrent limit exceeded", "limit", limit, "error", err.Error())
+			WriteJSONError(w, r.URL.Query(), "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer sem.Release()
+		fn(w, r)
+	}
+}
+
+// LimitReaderMiddleware caps how many request body bytes a handler may
+// read.
+func LimitReaderMiddleware(maxBytes int64, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		fn(w, r)
+	}
+}
+
+// CORSConfig configures CORSMiddleware. Zero values fall back to the
+// defaults.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API. The
+	// single entry "*" allows any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         time.Duration
+	// Router, when set, answers preflights with the methods registered
+	// on the matched route instead of the static AllowedMethods list,
+	// which stays as the fallback for unmatched paths.
+	Router *mux.Router
+}
+
+func (c CORSConfig) withDefaults() CORSConfig {
+	if len(c.AllowedOrigins) == 0 {
+		c.AllowedOrigins = []string{"*"}
+	}
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	}
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = []string{"Content-Type", "Authorization"}
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = 10 * time.Minute
+	}
+	return c
+}
+
+func (c CORSConfig) originAllowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMethods returns the methods cfg.Router has registered for the
+// request's path. It probes the router with each candidate method, and
+// reads the matched route's full method list; nil means no route
+// matched or the route accepts any method.
+func routeMethods(router *mux.Router, r *http.Request) []string {
+	probe := r.Clone(r.Context())
+	for _, m := range []string{
+		http.MethodGet, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodHead,
+	} {
+		probe.Method = m
+		var match mux.RouteMatch
+		if !router.Match(probe, &match) || match.Route == nil {
+			continue
+		}
+		methods, err := match.Route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		return methods
+	}
+	return nil
+}
+
+// CORSMiddleware answers CORS preflight requests and sets the
+// Access-Control-Allow-* headers on actual requests.
+func CORSMiddleware(cfg CORSConfig, fn http.HandlerFunc) http.HandlerFunc {
+	cfg = cfg.withDefaults()
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !cfg.originAllowed(origin) {
+			fn(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			allowMethods := methods
+			if cfg.Router != nil {
+				if ms := routeMethods(cfg.Router, r); len(ms) > 0 {
+					allowMethods = strings.Join(ms, ", ")
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fn(w, r)
+	}
+}
@@ -0,0 +1,446 @@
+package httputils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/devchiran/golang-demo/mock"
+	"github.com/devchiran/golang-demo/tools/clock"
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+// bucketStats records stats calls and bucket registrations.
+type bucketStats struct {
+	registered map[string][]float64
+	histograms []string
+}
+
+func (s *bucketStats) Count(string, float64, ...string) {}
+func (s *bucketStats) Gauge(string, float64, ...string) {}
+
+func (s *bucketStats) Histogram(name string, _ float64, _ ...string) {
+	s.histograms = append(s.histograms, name)
+}
+
+func (s *bucketStats) Handler() http.Handler { return nil }
+
+func (s *bucketStats) RegisterBuckets(name string, buckets []float64) {
+	s.registered[name] = buckets
+}
+
+// gaugeStats records gauge observations per metric.
+type gaugeStats struct {
+	mu     sync.Mutex
+	gauges map[string][]float64
+}
+
+func (s *gaugeStats) Count(string, float64, ...string)     {}
+func (s *gaugeStats) Histogram(string, float64, ...string) {}
+func (s *gaugeStats) Handler() http.Handler                { return nil }
+
+func (s *gaugeStats) Gauge(name string, value float64, _ ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gauges[name] = append(s.gauges[name], value)
+}
+
+func (s *gaugeStats) last(name string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vals := s.gauges[name]
+	if len(vals) == 0 {
+		return 0, false
+	}
+	return vals[len(vals)-1], true
+}
+
+func TestDrainMiddleware(t *testing.T) {
+	draining := false
+	h := DrainMiddleware(func() bool { return draining }, &mock.Logger{}, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 before draining, got %d", rec.Code)
+	}
+
+	draining = true
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while draining, got %d", rec.Code)
+	}
+
+	draining = false
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after draining cleared, got %d", rec.Code)
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	logger := &mock.Logger{}
+	sc := &bucketStats{registered: map[string][]float64{}}
+	h := AccessLogMiddleware(logger, sc, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created")) //nolint:errcheck
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/album", nil)
+	req.RemoteAddr = "198.51.100.7:61234"
+	h(httptest.NewRecorder(), req)
+
+	entries := logger.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	fields := map[string]interface{}{}
+	kv := entries[0].KeyVals
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields[kv[i].(string)] = kv[i+1]
+	}
+	for _, key := range []string{"http.method", "http.status", "http.route", "http.duration_ms", "http.bytes", "net.peer.ip"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("missing field %s in %v", key, fields)
+		}
+	}
+	if fields["http.method"] != http.MethodPost {
+		t.Errorf("http.method = %v, want POST", fields["http.method"])
+	}
+	if fields["http.status"] != http.StatusCreated {
+		t.Errorf("http.status = %v, want 201", fields["http.status"])
+	}
+	if fields["http.bytes"] != int64(len("created")) {
+		t.Errorf("http.bytes = %v, want %d", fields["http.bytes"], len("created"))
+	}
+	if fields["net.peer.ip"] != "198.51.100.7" {
+		t.Errorf("net.peer.ip = %v, want the address without the port", fields["net.peer.ip"])
+	}
+
+	histograms := map[string]bool{}
+	for _, name := range sc.histograms {
+		histograms[name] = true
+	}
+	if !histograms["http.duration_ms"] || !histograms["http.bytes"] {
+		t.Errorf("recorded histograms %v, want http.duration_ms and http.bytes", sc.histograms)
+	}
+}
+
+func TestRecoverMiddlewareCtxPassesContext(t *testing.T) {
+	logger := &mock.Logger{}
+	h := RecoverMiddlewareCtx(logger, func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(requestid.Set(req.Context(), "req-7"))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	entries := logger.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if entries[0].Ctx == nil {
+		t.Fatal("expected the request context to be passed to the logger")
+	}
+	if got := requestid.Get(entries[0].Ctx); got != "req-7" {
+		t.Errorf("request id from logged context = %q, want req-7", got)
+	}
+}
+
+func TestInFlightMiddleware(t *testing.T) {
+	sc := &gaugeStats{gauges: map[string][]float64{}}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := InFlightMiddleware(sc, "http", func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	<-entered
+	if v, ok := sc.last("http.in_flight"); !ok || v != 1 {
+		t.Errorf("in-flight gauge during handler = %v (%v), want 1", v, ok)
+	}
+	close(release)
+	<-done
+	if v, ok := sc.last("http.in_flight"); !ok || v != 0 {
+		t.Errorf("in-flight gauge after handler = %v (%v), want 0", v, ok)
+	}
+}
+
+func TestInFlightMiddlewareCountsOutPanics(t *testing.T) {
+	sc := &gaugeStats{gauges: map[string][]float64{}}
+	h := InFlightMiddleware(sc, "http", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() { recover() }() //nolint:errcheck
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if v, ok := sc.last("http.in_flight"); !ok || v != 0 {
+		t.Errorf("in-flight gauge after panic = %v (%v), want 0", v, ok)
+	}
+}
+
+func TestCORSPreflightReflectsRouteMethods(t *testing.T) {
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/thing/{id}", ok).Methods(http.MethodGet, http.MethodPatch, http.MethodDelete)
+
+	h := CORSMiddleware(CORSConfig{Router: router}, router.ServeHTTP)
+
+	preflight := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	t.Run("matched route lists its methods", func(t *testing.T) {
+		rec := preflight("/thing/42")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", rec.Code)
+		}
+		got := rec.Header().Get("Access-Control-Allow-Methods")
+		for _, m := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+			if !strings.Contains(got, m) {
+				t.Errorf("allow-methods %q is missing %s", got, m)
+			}
+		}
+		if strings.Contains(got, http.MethodPost) {
+			t.Errorf("allow-methods %q lists POST, which is not registered", got)
+		}
+	})
+
+	t.Run("unmatched path falls back to the defaults", func(t *testing.T) {
+		got := preflight("/elsewhere").Header().Get("Access-Control-Allow-Methods")
+		want := strings.Join(CORSConfig{}.withDefaults().AllowedMethods, ", ")
+		if got != want {
+			t.Errorf("allow-methods = %q, want the default %q", got, want)
+		}
+	})
+}
+
+func TestStatsMiddlewareWithBuckets(t *testing.T) {
+	sc := &bucketStats{registered: map[string][]float64{}}
+	buckets := []float64{0.001, 0.01}
+	h := StatsMiddlewareWithBuckets(sc, "internal", buckets, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	got, ok := sc.registered["internal.duration_seconds"]
+	if !ok {
+		t.Fatal("expected buckets to be registered for the duration histogram")
+	}
+	if len(got) != len(buckets) || got[0] != buckets[0] || got[1] != buckets[1] {
+		t.Errorf("registered buckets = %v, want %v", got, buckets)
+	}
+	if len(sc.histograms) != 1 || sc.histograms[0] != "internal.duration_seconds" {
+		t.Errorf("histogram observations = %v, want one internal.duration_seconds", sc.histograms)
+	}
+}
+
+// countStats records the tags of each Count call.
+type countStats struct {
+	calls [][]string
+}
+
+func (s *countStats) Count(_ string, _ float64, tags ...string) {
+	s.calls = append(s.calls, tags)
+}
+func (s *countStats) Gauge(string, float64, ...string)     {}
+func (s *countStats) Histogram(string, float64, ...string) {}
+func (s *countStats) Handler() http.Handler                { return nil }
+
+func TestStatsRouteMiddlewareLabels(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	routeTag := func(sc *countStats) string {
+		t.Helper()
+		if len(sc.calls) != 1 {
+			t.Fatalf("got %d Count calls, want 1", len(sc.calls))
+		}
+		for _, tag := range sc.calls[0] {
+			if strings.HasPrefix(tag, "route:") {
+				return strings.TrimPrefix(tag, "route:")
+			}
+		}
+		t.Fatalf("no route tag in %v", sc.calls[0])
+		return ""
+	}
+
+	t.Run("matched route uses its name", func(t *testing.T) {
+		sc := &countStats{}
+		router := mux.NewRouter()
+		router.HandleFunc("/thing", ok).Methods(http.MethodGet).Name("thing")
+		router.Use(func(next http.Handler) http.Handler {
+			return StatsRouteMiddleware(sc, next.ServeHTTP)
+		})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/thing", nil))
+		if got := routeTag(sc); got != "thing" {
+			t.Errorf("route label = %q, want thing", got)
+		}
+	})
+
+	t.Run("unknown path labels not_found", func(t *testing.T) {
+		sc := &countStats{}
+		router := mux.NewRouter()
+		router.HandleFunc("/thing", ok).Methods(http.MethodGet).Name("thing")
+		h := StatsRouteMiddleware(sc, router.ServeHTTP)
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+		if got := routeTag(sc); got != "not_found" {
+			t.Errorf("route label = %q, want not_found", got)
+		}
+	})
+
+	t.Run("wrong method labels method_not_allowed", func(t *testing.T) {
+		sc := &countStats{}
+		router := mux.NewRouter()
+		router.HandleFunc("/thing", ok).Methods(http.MethodGet).Name("thing")
+		h := StatsRouteMiddleware(sc, router.ServeHTTP)
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/thing", nil))
+		if got := routeTag(sc); got != "method_not_allowed" {
+			t.Errorf("route label = %q, want method_not_allowed", got)
+		}
+	})
+}
+
+func TestStatsMiddlewaresTolerateNilClient(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	for name, h := range map[string]http.HandlerFunc{
+		"stats":       StatsMiddleware(nil, "http", ok),
+		"stats route": StatsRouteMiddleware(nil, ok),
+		"in flight":   InFlightMiddleware(nil, "http", ok),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected the request to succeed, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareExcept(t *testing.T) {
+	var hasDeadline bool
+	handler := TimeoutMiddlewareExcept(time.Minute, func(r *http.Request) bool {
+		return r.URL.Path == "/stream"
+	}, func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if hasDeadline {
+		t.Error("expected no deadline on a skipped route")
+	}
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+	if !hasDeadline {
+		t.Error("expected a deadline on an ordinary route")
+	}
+}
+
+func TestRecoverMiddlewareAbortsPartialResponse(t *testing.T) {
+	handler := RecoverMiddleware(&mock.Logger{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial")) //nolint:errcheck
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		panic("mid-body failure")
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		// The abort may already surface while reading the header.
+		return
+	}
+	defer res.Body.Close()
+	if _, err := io.ReadAll(res.Body); err == nil {
+		t.Fatal("expected reading the truncated body to fail, got a clean EOF")
+	}
+}
+
+// durationStats records histogram values by name.
+type durationStats struct {
+	histograms map[string]float64
+}
+
+func (s *durationStats) Count(string, float64, ...string) {}
+func (s *durationStats) Gauge(string, float64, ...string) {}
+
+func (s *durationStats) Histogram(name string, value float64, _ ...string) {
+	s.histograms[name] = value
+}
+
+func (s *durationStats) Handler() http.Handler { return nil }
+
+func TestMiddlewareDurationsUseInjectedClock(t *testing.T) {
+	fake := clock.NewFake()
+	advance := func(w http.ResponseWriter, _ *http.Request) {
+		fake.Advance(250 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	t.Run("logging", func(t *testing.T) {
+		logger := &mock.Logger{}
+		h := LoggingMiddlewareWithClock(logger, fake, advance)
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		entries := logger.Entries()
+		if len(entries) != 1 {
+			t.Fatalf("got %d log entries, want 1", len(entries))
+		}
+		kv := entries[0].KeyVals
+		var logged string
+		for i := 0; i+1 < len(kv); i += 2 {
+			if kv[i] == "duration" {
+				logged, _ = kv[i+1].(string)
+			}
+		}
+		if logged != "250ms" {
+			t.Errorf("logged duration = %q, want 250ms", logged)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		sc := &durationStats{histograms: map[string]float64{}}
+		h := StatsMiddlewareWithClock(sc, "http", fake, advance)
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := sc.histograms["http.duration_seconds"]; got != 0.25 {
+			t.Errorf("recorded duration = %v, want 0.25", got)
+		}
+	})
+}
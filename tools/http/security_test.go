@@ -0,0 +1,62 @@
+package httputils
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	handler := SecurityHeadersMiddleware(SecurityConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("defaults on plaintext request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		for header, want := range map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+			"Referrer-Policy":        "no-referrer",
+		} {
+			if got := rec.Header().Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+		if got := rec.Header().Get("Strict-Transport-Security"); got != "" {
+			t.Errorf("expected no HSTS header on a plaintext request, got %q", got)
+		}
+		if got := rec.Header().Get("Content-Security-Policy"); got != "" {
+			t.Errorf("expected no CSP header by default, got %q", got)
+		}
+	})
+
+	t.Run("hsts on tls request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+		req.TLS = &tls.ConnectionState{}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=15552000" {
+			t.Errorf("Strict-Transport-Security = %q, want max-age=15552000", got)
+		}
+	})
+
+	t.Run("configured values", func(t *testing.T) {
+		handler := SecurityHeadersMiddleware(SecurityConfig{
+			FrameOptions:          "SAMEORIGIN",
+			ContentSecurityPolicy: "default-src 'none'",
+		}, func(w http.ResponseWriter, r *http.Request) {})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := rec.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+			t.Errorf("X-Frame-Options = %q, want SAMEORIGIN", got)
+		}
+		if got := rec.Header().Get("Content-Security-Policy"); got != "default-src 'none'" {
+			t.Errorf("Content-Security-Policy = %q, want default-src 'none'", got)
+		}
+	})
+}
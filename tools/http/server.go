@@ -0,0 +1,152 @@
+package httputils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultReadTimeout       = 30 * time.Second
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+type serverOptions struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	// writeTimeoutSet records that WithWriteTimeout was called, so an
+	// explicit zero means "no timeout" instead of the default.
+	writeTimeoutSet bool
+	idleTimeout     time.Duration
+	baseContext     func(net.Listener) context.Context
+	connContext     func(ctx context.Context, c net.Conn) context.Context
+}
+
+// ServerOption tunes the server built by NewServer.
+type ServerOption func(*serverOptions)
+
+// WithReadTimeout sets the maximum duration for reading a request.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(o *serverOptions) { o.readTimeout = d }
+}
+
+// WithWriteTimeout sets the maximum duration for writing a response.
+// An explicit zero (or negative) disables the timeout entirely, which
+// long-lived streaming responses like SSE need; the trade-off is that
+// a stalled client can then hold its connection open indefinitely, so
+// pair it with an idle timeout and connection limits.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		if d < 0 {
+			d = 0
+		}
+		o.writeTimeout = d
+		o.writeTimeoutSet = true
+	}
+}
+
+// WithIdleTimeout sets how long idle keep-alive connections are kept.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(o *serverOptions) { o.idleTimeout = d }
+}
+
+// WithBaseContext sets the context every request's context descends
+// from, so app-wide values (logger, tenant resolver, ...) are available
+// without a middleware each.
+func WithBaseContext(fn func(net.Listener) context.Context) ServerOption {
+	return func(o *serverOptions) { o.baseContext = fn }
+}
+
+// WithConnContext derives per-connection context values on top of the
+// base context.
+func WithConnContext(fn func(ctx context.Context, c net.Conn) context.Context) ServerOption {
+	return func(o *serverOptions) { o.connContext = fn }
+}
+
+// NewServer builds an *http.Server with sane timeout defaults.
+func NewServer(addr string, h http.Handler, opts ...ServerOption) *http.Server {
+	var o serverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.readTimeout <= 0 {
+		o.readTimeout = defaultReadTimeout
+	}
+	if o.readHeaderTimeout <= 0 {
+		o.readHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if o.writeTimeout <= 0 && !o.writeTimeoutSet {
+		o.writeTimeout = defaultWriteTimeout
+	}
+	if o.idleTimeout <= 0 {
+		o.idleTimeout = defaultIdleTimeout
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadTimeout:       o.readTimeout,
+		ReadHeaderTimeout: o.readHeaderTimeout,
+		WriteTimeout:      o.writeTimeout,
+		IdleTimeout:       o.idleTimeout,
+		BaseContext:       o.baseContext,
+		ConnContext:       o.connContext,
+	}
+}
+
+// tcpKeepAliveListener enables TCP keep-alives on accepted connections,
+// mirroring what net/http does for its default listener. It records its
+// own Close, so an Accept failure caused by Shutdown closing the
+// listener can be told apart from a genuine accept error.
+type tcpKeepAliveListener struct {
+	*net.TCPListener
+	period time.Duration
+	closed *int32
+}
+
+func (ln tcpKeepAliveListener) Accept() (net.Conn, error) {
+	tc, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	tc.SetKeepAlive(true)            //nolint:errcheck
+	tc.SetKeepAlivePeriod(ln.period) //nolint:errcheck
+	return tc, nil
+}
+
+func (ln tcpKeepAliveListener) Close() error {
+	atomic.StoreInt32(ln.closed, 1)
+	return ln.TCPListener.Close()
+}
+
+// ListenAndServe listens on s.Addr and serves with TCP keep-alives set
+// to the given period. Like http.Server.ListenAndServe, it returns
+// http.ErrServerClosed after Shutdown (or Close); a raw "use of closed
+// network connection" from the Accept loop — which Shutdown can surface
+// on some platforms — is mapped onto the same sentinel, so only real
+// accept failures reach the caller as errors.
+func ListenAndServe(s *http.Server, keepAlivePeriod time.Duration) error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "listening on %s", addr)
+	}
+	var closed int32
+	err = s.Serve(tcpKeepAliveListener{ln.(*net.TCPListener), keepAlivePeriod, &closed})
+	// Shutdown closes the listener before Serve notices it is shutting
+	// down; when that race surfaces the closed-listener error raw, keep
+	// the documented sentinel.
+	if errors.Is(err, net.ErrClosed) && atomic.LoadInt32(&closed) == 1 {
+		return http.ErrServerClosed
+	}
+	return err
+}
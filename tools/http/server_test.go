@@ -0,0 +1,87 @@
+package httputils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type baseCtxKey struct{}
+
+func TestWithBaseContext(t *testing.T) {
+	got := make(chan interface{}, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Context().Value(baseCtxKey{})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := NewServer("127.0.0.1:0", handler, WithBaseContext(func(net.Listener) context.Context {
+		return context.WithValue(context.Background(), baseCtxKey{}, "app-value")
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go srv.Serve(ln) //nolint:errcheck
+	defer srv.Close()
+
+	res, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("requesting: %v", err)
+	}
+	res.Body.Close()
+
+	if v := <-got; v != "app-value" {
+		t.Errorf("base context value = %v, want app-value", v)
+	}
+}
+
+func TestWithWriteTimeoutZeroDisablesTimeout(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := NewServer(":0", handler, WithWriteTimeout(0))
+	if srv.WriteTimeout != 0 {
+		t.Errorf("WriteTimeout = %v, want an explicit zero to disable it", srv.WriteTimeout)
+	}
+
+	srv = NewServer(":0", handler)
+	if srv.WriteTimeout != defaultWriteTimeout {
+		t.Errorf("WriteTimeout = %v, want the %v default when unset", srv.WriteTimeout, defaultWriteTimeout)
+	}
+
+	srv = NewServer(":0", handler, WithWriteTimeout(time.Minute))
+	if srv.WriteTimeout != time.Minute {
+		t.Errorf("WriteTimeout = %v, want the configured minute", srv.WriteTimeout)
+	}
+}
+
+func TestListenAndServeReturnsSentinelOnShutdown(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := NewServer("127.0.0.1:0", handler)
+
+	served := make(chan error, 1)
+	go func() { served <- ListenAndServe(srv, time.Second) }()
+
+	// Give the Accept loop a moment to start before shutting down.
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	select {
+	case err := <-served:
+		if err != http.ErrServerClosed {
+			t.Errorf("ListenAndServe = %v, want http.ErrServerClosed after Shutdown", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ListenAndServe did not return after Shutdown")
+	}
+}
@@ -0,0 +1,26 @@
+package httputils
+
+import (
+	"net/http"
+	"time"
+)
+
+// VersionHandler reports the service name, version and current time in
+// the standard data envelope. The ETag covers only name and version —
+// the time field changes every call — so pollers that send
+// If-None-Match get a 304 until the service is redeployed.
+func VersionHandler(name, version string) http.HandlerFunc {
+	etag := WeakETag(name, version)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if IfNoneMatch(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		WriteJSONData(w, r.URL.Query(), map[string]string{
+			"name":    name,
+			"version": version,
+			"time":    time.Now().UTC().Format(time.RFC3339),
+		}, http.StatusOK)
+	}
+}
@@ -0,0 +1,59 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionHandlerETag(t *testing.T) {
+	h := VersionHandler("golang-demo", "1.2.3")
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	t.Run("matching if-none-match yields 304", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		if rec.Code != http.StatusNotModified {
+			t.Fatalf("expected 304, got %d", rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("expected empty body, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("mismatched if-none-match yields fresh body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.Header.Set("If-None-Match", `W/"stale"`)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if body.Data["name"] != "golang-demo" || body.Data["version"] != "1.2.3" {
+			t.Errorf("body = %v, want name and version", body.Data)
+		}
+		if body.Data["time"] == "" {
+			t.Error("expected the time field to remain in the body")
+		}
+	})
+}
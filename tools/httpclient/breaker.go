@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools/clock"
+)
+
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerWindow   = time.Minute
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// BreakerState is the per-host circuit breaker state.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through, counting failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails requests fast until the cooldown passes.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial request decide whether to
+	// close again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// OpenError is returned, without touching the network, while a host's
+// breaker is open.
+type OpenError struct {
+	Host string
+}
+
+func (e *OpenError) Error() string {
+	return "circuit open for " + e.Host
+}
+
+// BreakerConfig configures WithCircuitBreaker. The zero value gets
+// usable defaults.
+type BreakerConfig struct {
+	// Failures is how many consecutive failures within Window open the
+	// breaker. Connection errors and 5xx responses count as failures.
+	Failures int
+	// Window bounds how far apart consecutive failures may be and
+	// still accumulate.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before allowing a
+	// half-open trial request.
+	Cooldown time.Duration
+	// OnStateChange, when set, observes every transition.
+	OnStateChange func(host string, from, to BreakerState)
+	// Clock substitutes time, for tests.
+	Clock clock.Clock
+}
+
+// WithCircuitBreaker wraps the transport with a per-host circuit
+// breaker so downstream outages fail fast instead of waiting out the
+// full timeout on every request.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(o *options) { o.breaker = &cfg }
+}
+
+type hostBreaker struct {
+	state        BreakerState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	trial        bool
+}
+
+type breakerTransport struct {
+	next  http.RoundTripper
+	cfg   BreakerConfig
+	clock clock.Clock
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+func newBreakerTransport(cfg BreakerConfig, next http.RoundTripper) *breakerTransport {
+	if cfg.Failures <= 0 {
+		cfg.Failures = defaultBreakerFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultBreakerWindow
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultBreakerCooldown
+	}
+	cl := cfg.Clock
+	if cl == nil {
+		cl = clock.New()
+	}
+	return &breakerTransport{next: next, cfg: cfg, clock: cl, hosts: map[string]*hostBreaker{}}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	if err := t.admit(host); err != nil {
+		return nil, err
+	}
+
+	res, err := t.next.RoundTrip(req)
+	t.record(host, err != nil || res.StatusCode >= http.StatusInternalServerError)
+	return res, err
+}
+
+// admit decides whether a request to host may proceed, moving an open
+// breaker to half-open once the cooldown has passed.
+func (t *breakerTransport) admit(host string) error {
+	t.mu.Lock()
+	b, ok := t.hosts[host]
+	if !ok {
+		b = &hostBreaker{}
+		t.hosts[host] = b
+	}
+	var transition func()
+	defer func() {
+		t.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	switch b.state {
+	case BreakerOpen:
+		if t.clock.Since(b.openedAt) < t.cfg.Cooldown {
+			return &OpenError{Host: host}
+		}
+		transition = t.transition(host, b, BreakerHalfOpen)
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return &OpenError{Host: host}
+		}
+		b.trial = true
+		return nil
+	}
+	return nil
+}
+
+// record feeds a request outcome back into host's breaker.
+func (t *breakerTransport) record(host string, failed bool) {
+	t.mu.Lock()
+	b := t.hosts[host]
+	var transition func()
+	defer func() {
+		t.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	if b.state == BreakerHalfOpen {
+		b.trial = false
+		if failed {
+			transition = t.transition(host, b, BreakerOpen)
+			b.openedAt = t.clock.Now()
+			return
+		}
+		transition = t.transition(host, b, BreakerClosed)
+		b.failures = 0
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	now := t.clock.Now()
+	if b.failures == 0 || now.Sub(b.firstFailure) > t.cfg.Window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	if b.failures++; b.failures >= t.cfg.Failures {
+		transition = t.transition(host, b, BreakerOpen)
+		b.openedAt = now
+	}
+}
+
+// transition moves b to the new state and returns the callback to run
+// once the lock is released, or nil.
+func (t *breakerTransport) transition(host string, b *hostBreaker, to BreakerState) func() {
+	from := b.state
+	b.state = to
+	if t.cfg.OnStateChange == nil || from == to {
+		return nil
+	}
+	return func() { t.cfg.OnStateChange(host, from, to) }
+}
@@ -0,0 +1,146 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/devchiran/golang-demo/mock"
+	"github.com/devchiran/golang-demo/tools/clock"
+)
+
+type stateChange struct {
+	host     string
+	from, to BreakerState
+}
+
+func TestCircuitBreakerLifecycle(t *testing.T) {
+	fc := clock.NewFake()
+	var transitions []stateChange
+	failing := true
+	inner := &mock.HTTPClient{
+		DoFn: func(*http.Request) (*http.Response, error) {
+			if failing {
+				return nil, errors.New("connection refused")
+			}
+			return httptest.NewRecorder().Result(), nil
+		},
+	}
+	bt := newBreakerTransport(BreakerConfig{
+		Failures: 2,
+		Window:   time.Minute,
+		Cooldown: 10 * time.Second,
+		Clock:    fc,
+		OnStateChange: func(host string, from, to BreakerState) {
+			transitions = append(transitions, stateChange{host, from, to})
+		},
+	}, inner)
+
+	do := func() error {
+		req := httptest.NewRequest(http.MethodGet, "http://backend:8080/x", nil)
+		res, err := bt.RoundTrip(req)
+		if res != nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	// Two consecutive failures trip the breaker.
+	for i := 0; i < 2; i++ {
+		if err := do(); err == nil {
+			t.Fatalf("request %d: expected the inner error", i)
+		}
+	}
+
+	// While open, requests fail fast with the typed error.
+	err := do()
+	if _, ok := err.(*OpenError); !ok {
+		t.Fatalf("expected *OpenError while open, got %v", err)
+	}
+
+	// After the cooldown a half-open trial goes through; it succeeds,
+	// closing the breaker again.
+	failing = false
+	fc.Advance(11 * time.Second)
+	if err := do(); err != nil {
+		t.Fatalf("expected the half-open trial to pass, got %v", err)
+	}
+	if err := do(); err != nil {
+		t.Fatalf("expected requests to flow once closed, got %v", err)
+	}
+
+	want := []stateChange{
+		{"backend:8080", BreakerClosed, BreakerOpen},
+		{"backend:8080", BreakerOpen, BreakerHalfOpen},
+		{"backend:8080", BreakerHalfOpen, BreakerClosed},
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("got transitions %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d = %v, want %v", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	fc := clock.NewFake()
+	inner := &mock.HTTPClient{
+		DoFn: func(*http.Request) (*http.Response, error) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(http.StatusBadGateway)
+			return rec.Result(), nil
+		},
+	}
+	bt := newBreakerTransport(BreakerConfig{Failures: 1, Cooldown: 10 * time.Second, Clock: fc}, inner)
+
+	do := func() (*http.Response, error) {
+		return bt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://backend/x", nil))
+	}
+
+	// A 5xx counts as a failure and opens the breaker immediately.
+	if res, err := do(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else {
+		res.Body.Close()
+	}
+	if _, err := do(); err == nil {
+		t.Fatal("expected fail-fast while open")
+	}
+
+	// The failed half-open trial reopens the breaker.
+	fc.Advance(11 * time.Second)
+	if res, err := do(); err != nil {
+		t.Fatalf("expected the trial to reach the backend, got %v", err)
+	} else {
+		res.Body.Close()
+	}
+	if _, err := do(); err == nil {
+		t.Fatal("expected fail-fast after the failed trial")
+	}
+}
+
+func TestCircuitBreakerIsPerHost(t *testing.T) {
+	inner := &mock.HTTPClient{
+		DoFn: func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "bad" {
+				return nil, errors.New("connection refused")
+			}
+			return httptest.NewRecorder().Result(), nil
+		},
+	}
+	bt := newBreakerTransport(BreakerConfig{Failures: 1}, inner)
+
+	if _, err := bt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://bad/x", nil)); err == nil {
+		t.Fatal("expected the bad host to fail")
+	}
+	res, err := bt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://good/x", nil))
+	if err != nil {
+		t.Fatalf("expected the good host to be unaffected, got %v", err)
+	}
+	res.Body.Close()
+}
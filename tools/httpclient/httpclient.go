@@ -0,0 +1,209 @@
+// Package httpclient builds http.Clients with the transport tuning the
+// services need when calling each other and third parties.
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTimeout         = 30 * time.Second
+	defaultDialTimeout     = 10 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	defaultMaxIdleConns    = 100
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// ErrResponseTooLarge is returned by response body reads once a body
+// exceeds the limit set with WithMaxResponseBytes.
+var ErrResponseTooLarge = errors.New("httpclient: response body too large")
+
+type options struct {
+	timeout          time.Duration
+	dialer           *net.Dialer
+	disableHTTP2     bool
+	tlsConfig        *tls.Config
+	breaker          *BreakerConfig
+	defaultHeaders   http.Header
+	maxResponseBytes int64
+}
+
+// Option tunes the client built by New.
+type Option func(*options)
+
+// WithTimeout sets the end-to-end request timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithDialer substitutes the dialer used for new connections.
+func WithDialer(d *net.Dialer) Option {
+	return func(o *options) { o.dialer = d }
+}
+
+// WithDisableHTTP2 restricts the client to HTTP/1.1, for backends whose
+// HTTP/2 implementations misbehave.
+func WithDisableHTTP2() Option {
+	return func(o *options) { o.disableHTTP2 = true }
+}
+
+// WithTLSConfig sets the TLS configuration used for new connections,
+// e.g. client certificates for mTLS or pinned roots. It composes with
+// WithDisableHTTP2, which only rewrites the protocol negotiation map.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) { o.tlsConfig = cfg }
+}
+
+// WithDefaultHeaders sets h on every outgoing request, for static
+// headers like API keys or a service identity. Headers the request
+// already carries are left alone, so per-request values always win.
+func WithDefaultHeaders(h http.Header) Option {
+	return func(o *options) { o.defaultHeaders = h }
+}
+
+// WithMaxResponseBytes caps how much of any response body the client
+// will hand out. Reading past n bytes fails with ErrResponseTooLarge,
+// so a runaway downstream cannot balloon memory in callers that slurp
+// bodies whole.
+func WithMaxResponseBytes(n int64) Option {
+	return func(o *options) { o.maxResponseBytes = n }
+}
+
+// New builds an *http.Client with sane transport defaults.
+func New(opts ...Option) *http.Client {
+	o := options{
+		timeout: defaultTimeout,
+		dialer:  &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultKeepAlive},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	t := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DialContext:       o.dialer.DialContext,
+		ForceAttemptHTTP2: !o.disableHTTP2,
+		MaxIdleConns:      defaultMaxIdleConns,
+		IdleConnTimeout:   defaultIdleConnTimeout,
+		TLSClientConfig:   o.tlsConfig,
+	}
+	if o.disableHTTP2 {
+		// An empty, non-nil map opts out of the bundled HTTP/2 support.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	var rt http.RoundTripper = t
+	if o.breaker != nil {
+		rt = newBreakerTransport(*o.breaker, rt)
+	}
+	if len(o.defaultHeaders) > 0 {
+		rt = &headerTransport{next: rt, headers: o.defaultHeaders}
+	}
+	if o.maxResponseBytes > 0 {
+		rt = &limitTransport{next: rt, max: o.maxResponseBytes}
+	}
+	// The timeout lives in the transport rather than http.Client.Timeout
+	// so a request that brings its own context deadline is not cut short
+	// by the client-wide default.
+	rt = &timeoutTransport{next: rt, timeout: o.timeout}
+	return &http.Client{Transport: rt}
+}
+
+// headerTransport fills in the configured default headers on requests
+// that do not already set them. The request is cloned first, as
+// RoundTrippers must not mutate their input.
+type headerTransport struct {
+	next    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	for k, vals := range t.headers {
+		if len(out.Header.Values(k)) == 0 {
+			out.Header[http.CanonicalHeaderKey(k)] = vals
+		}
+	}
+	return t.next.RoundTrip(out)
+}
+
+// limitTransport wraps every response body so reads fail once the
+// configured number of bytes has been consumed. Sitting in the
+// transport protects all responses, whatever code path reads them.
+type limitTransport struct {
+	next http.RoundTripper
+	max  int64
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &limitBody{body: res.Body, remaining: t.max}
+	return res, nil
+}
+
+// limitBody reads at most one byte past its budget; seeing that extra
+// byte proves the body is oversized and turns every subsequent read
+// into ErrResponseTooLarge. A body of exactly the limit reads fully.
+type limitBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (b *limitBody) Close() error {
+	return b.body.Close()
+}
+
+// timeoutTransport applies the client-wide timeout as a context
+// deadline, but only to requests that do not already carry one.
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Deadline(); ok || t.timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The deadline keeps covering the body; cancel once it is closed.
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
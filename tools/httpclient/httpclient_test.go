@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+// innerTransport unwraps the client down to the *http.Transport.
+func innerTransport(t *testing.T, c *http.Client) *http.Transport {
+	t.Helper()
+	rt := c.Transport
+	for {
+		switch v := rt.(type) {
+		case *http.Transport:
+			return v
+		case *timeoutTransport:
+			rt = v.next
+		case *breakerTransport:
+			rt = v.next
+		case *headerTransport:
+			rt = v.next
+		case *limitTransport:
+			rt = v.next
+		default:
+			t.Fatalf("unexpected transport %T", rt)
+		}
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	cfg := &tls.Config{ServerName: "internal.example.com"}
+	c := New(WithTLSConfig(cfg))
+
+	tr := innerTransport(t, c)
+	if tr.TLSClientConfig != cfg {
+		t.Error("expected the transport to carry the provided tls.Config")
+	}
+	if !tr.ForceAttemptHTTP2 {
+		t.Error("expected HTTP/2 to stay enabled by default")
+	}
+}
+
+func TestDefaultTimeoutAppliesWithoutDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithTimeout(50 * time.Millisecond))
+	if c.Timeout != 0 {
+		t.Fatal("the fixed http.Client.Timeout must be unset; it would override per-request deadlines")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	start := time.Now()
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("expected the default timeout to fire")
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("timed out after %v, expected roughly the 50ms default", took)
+	}
+}
+
+func TestPerRequestDeadlineIsNotTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(WithTimeout(50 * time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("expected the longer per-request deadline to win, got %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestWithTLSConfigAndDisabledHTTP2(t *testing.T) {
+	cfg := &tls.Config{ServerName: "internal.example.com"}
+	c := New(WithTLSConfig(cfg), WithDisableHTTP2())
+
+	tr := innerTransport(t, c)
+	if tr.TLSClientConfig != cfg {
+		t.Error("disabling HTTP/2 must not drop the custom tls.Config")
+	}
+	if tr.TLSNextProto == nil || len(tr.TLSNextProto) != 0 {
+		t.Errorf("TLSNextProto = %v, want an empty non-nil map", tr.TLSNextProto)
+	}
+	if tr.ForceAttemptHTTP2 {
+		t.Error("expected ForceAttemptHTTP2 to be off when HTTP/2 is disabled")
+	}
+}
+
+func TestWithDefaultHeaders(t *testing.T) {
+	var seen http.Header
+	next := &mock.HTTPClient{
+		DoFn: func(req *http.Request) (*http.Response, error) {
+			seen = req.Header
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	}
+	ht := &headerTransport{
+		next: next,
+		headers: http.Header{
+			"X-Api-Key":    []string{"secret"},
+			"X-Service-Id": []string{"golang-demo"},
+		},
+	}
+
+	t.Run("defaults are applied", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://downstream/", nil)
+		if _, err := ht.RoundTrip(req); err != nil {
+			t.Fatalf("round trip: %v", err)
+		}
+		if got := seen.Get("X-Api-Key"); got != "secret" {
+			t.Errorf("X-Api-Key = %q, want secret", got)
+		}
+		if got := seen.Get("X-Service-Id"); got != "golang-demo" {
+			t.Errorf("X-Service-Id = %q, want golang-demo", got)
+		}
+	})
+
+	t.Run("per-request values win", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://downstream/", nil)
+		req.Header.Set("X-Api-Key", "override")
+		if _, err := ht.RoundTrip(req); err != nil {
+			t.Fatalf("round trip: %v", err)
+		}
+		if got := seen.Get("X-Api-Key"); got != "override" {
+			t.Errorf("X-Api-Key = %q, want the per-request override", got)
+		}
+		if req.Header.Get("X-Service-Id") != "" {
+			t.Error("the original request must not be mutated")
+		}
+	})
+}
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	const limit = 16
+	var body string
+	next := &mock.HTTPClient{
+		DoFn: func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+	lt := &limitTransport{next: next, max: limit}
+	read := func(t *testing.T) ([]byte, error) {
+		t.Helper()
+		res, err := lt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://downstream/", nil))
+		if err != nil {
+			t.Fatalf("round trip: %v", err)
+		}
+		defer res.Body.Close()
+		return io.ReadAll(res.Body)
+	}
+
+	t.Run("under the limit reads fully", func(t *testing.T) {
+		body = strings.Repeat("a", limit)
+		got, err := read(t)
+		if err != nil {
+			t.Fatalf("reading an at-limit body: %v", err)
+		}
+		if string(got) != body {
+			t.Errorf("read %d bytes, want the full %d-byte body", len(got), limit)
+		}
+	})
+
+	t.Run("over the limit fails", func(t *testing.T) {
+		body = strings.Repeat("a", limit+1)
+		if _, err := read(t); !errors.Is(err, ErrResponseTooLarge) {
+			t.Fatalf("reading an oversized body returned %v, want ErrResponseTooLarge", err)
+		}
+	})
+}
+
+func TestWithMaxResponseBytesWiredThroughNew(t *testing.T) {
+	c := New(WithMaxResponseBytes(1 << 20))
+	// innerTransport fails the test if it meets a transport it does not
+	// know, so reaching the *http.Transport proves the limit is in the
+	// chain.
+	if _, ok := c.Transport.(*timeoutTransport); !ok {
+		t.Fatalf("outermost transport is %T, want *timeoutTransport", c.Transport)
+	}
+	innerTransport(t, c)
+}
@@ -0,0 +1,57 @@
+// Package json wraps the standard library codec with friendlier,
+// wrapped errors.
+package json
+
+import (
+	"io"
+
+	gojson "encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Marshal encodes v as JSON.
+func Marshal(v interface{}) ([]byte, error) {
+	b, err := gojson.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshaling %T", v)
+	}
+	return b, nil
+}
+
+// MarshalIndent encodes v as indented JSON.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	b, err := gojson.MarshalIndent(v, prefix, indent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshaling %T", v)
+	}
+	return b, nil
+}
+
+// Unmarshal decodes data into v.
+func Unmarshal(data []byte, v interface{}) error {
+	if err := gojson.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "unmarshaling into %T", v)
+	}
+	return nil
+}
+
+// Decode decodes a single JSON value from r into v.
+func Decode(r io.Reader, v interface{}) error {
+	if err := gojson.NewDecoder(r).Decode(v); err != nil {
+		return errors.Wrapf(err, "decoding into %T", v)
+	}
+	return nil
+}
+
+// DecodeUseNumber decodes like Decode, but numbers land in interface{}
+// targets as json.Number instead of float64, so large integers keep
+// their precision.
+func DecodeUseNumber(r io.Reader, v interface{}) error {
+	dec := gojson.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return errors.Wrapf(err, "decoding into %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package json
+
+import (
+	gojson "encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeUseNumberKeepsPrecision(t *testing.T) {
+	// Beyond float64's 53-bit integer range; plain Decode would round it.
+	const id = "9007199254740993"
+
+	var v map[string]interface{}
+	if err := DecodeUseNumber(strings.NewReader(`{"id": `+id+`}`), &v); err != nil {
+		t.Fatalf("DecodeUseNumber: %v", err)
+	}
+	num, ok := v["id"].(gojson.Number)
+	if !ok {
+		t.Fatalf("id decoded as %T, want json.Number", v["id"])
+	}
+	if num.String() != id {
+		t.Errorf("id round-tripped as %s, want %s", num, id)
+	}
+	n, err := num.Int64()
+	if err != nil {
+		t.Fatalf("converting to int64: %v", err)
+	}
+	if n != 9007199254740993 {
+		t.Errorf("Int64 = %d, want 9007199254740993", n)
+	}
+}
+
+func TestDecodeUseNumberWrapsErrors(t *testing.T) {
+	var v map[string]interface{}
+	err := DecodeUseNumber(strings.NewReader(`{`), &v)
+	if err == nil {
+		t.Fatal("expected an error for truncated input")
+	}
+	if !strings.Contains(err.Error(), "decoding into") {
+		t.Errorf("error %q lacks the package's wrapping", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Package lifecycle coordinates the startup and graceful shutdown of
+// the long-running pieces of a process.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// LifeCycle runs a set of named processes and shuts them all down when
+// any one exits or the parent context is cancelled.
+type LifeCycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	logger tools.Logger
+
+	mu      sync.Mutex
+	closers []closer
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+// New derives the LifeCycle context from parent; cancelling parent (for
+// instance on SIGTERM) begins shutdown.
+func New(parent context.Context, logger tools.Logger) *LifeCycle {
+	ctx, cancel := context.WithCancel(parent)
+	return &LifeCycle{ctx: ctx, cancel: cancel, logger: logger}
+}
+
+// Context is cancelled when shutdown begins, either because the parent
+// context was cancelled or because a started process exited.
+func (lc *LifeCycle) Context() context.Context {
+	return lc.ctx
+}
+
+// Start runs fn on a goroutine. When fn returns or panics the whole
+// LifeCycle is cancelled so sibling processes shut down too.
+func (lc *LifeCycle) Start(name string, fn func() error) {
+	lc.wg.Add(1)
+	go func() {
+		defer lc.wg.Done()
+		defer lc.cancel()
+		defer func() {
+			if rec := recover(); rec != nil {
+				lc.logger.Error("process panicked", "name", name, "panic", fmt.Sprintf("%v", rec))
+			}
+		}()
+		if err := fn(); err != nil {
+			lc.logger.Error("process exited", "name", name, "error", err.Error())
+			return
+		}
+		lc.logger.Info("process exited", "name", name)
+	}()
+}
+
+// StartWithRestart runs fn like Start, but re-runs it after a panic or
+// error, waiting backoff between attempts, until it returns nil, the
+// restart budget is spent, or shutdown begins. A clean return or an
+// exhausted budget cancels the LifeCycle the same way Start does.
+func (lc *LifeCycle) StartWithRestart(name string, maxRestarts int, backoff time.Duration, fn func() error) {
+	lc.wg.Add(1)
+	go func() {
+		defer lc.wg.Done()
+		defer lc.cancel()
+		for restarts := 0; ; restarts++ {
+			err := lc.run(name, fn)
+			if err == nil {
+				lc.logger.Info("process exited", "name", name)
+				return
+			}
+			if restarts >= maxRestarts {
+				lc.logger.Error("process exited, restart budget exhausted",
+					"name", name, "restarts", restarts, "error", err.Error())
+				return
+			}
+			lc.logger.Warn("restarting process",
+				"name", name, "restart", restarts+1, "max_restarts", maxRestarts, "error", err.Error())
+			select {
+			case <-lc.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+		}
+	}()
+}
+
+// run invokes fn once, converting a panic into an error.
+func (lc *LifeCycle) run(name string, fn func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			lc.logger.Error("process panicked", "name", name, "panic", fmt.Sprintf("%v", rec))
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return fn()
+}
+
+// Defer registers fn to run during shutdown, after the started
+// processes have finished (or the wait timed out). Closers run in
+// reverse registration order.
+func (lc *LifeCycle) Defer(name string, fn func() error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.closers = append(lc.closers, closer{name: name, fn: fn})
+}
+
+// Wait blocks until shutdown begins, then waits up to timeout for the
+// started processes to finish before running the deferred closers.
+func (lc *LifeCycle) Wait(timeout time.Duration) error {
+	<-lc.ctx.Done()
+
+	done := make(chan struct{})
+	go func() {
+		lc.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		err = errors.New("lifecycle: timed out waiting for processes to finish")
+	}
+
+	lc.mu.Lock()
+	closers := lc.closers
+	lc.mu.Unlock()
+	for i := len(closers) - 1; i >= 0; i-- {
+		if cerr := closers[i].fn(); cerr != nil {
+			lc.logger.Error("closing", "name", closers[i].name, "error", cerr.Error())
+		}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package lifecycle
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/mock"
+)
+
+func TestStartWithRestartRecoversFromPanics(t *testing.T) {
+	logger := &mock.Logger{}
+	lc := New(context.Background(), logger)
+
+	var runs int32
+	lc.StartWithRestart("worker", 5, time.Millisecond, func() error {
+		if atomic.AddInt32(&runs, 1) <= 2 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	if err := lc.Wait(time.Second); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if got := atomic.LoadInt32(&runs); got != 3 {
+		t.Errorf("fn ran %d times, want 3", got)
+	}
+	var restarts int
+	for _, e := range logger.Entries() {
+		if e.Msg == "restarting process" {
+			restarts++
+		}
+	}
+	if restarts != 2 {
+		t.Errorf("logged %d restarts, want 2", restarts)
+	}
+}
+
+func TestStartWithRestartExhaustsBudget(t *testing.T) {
+	logger := &mock.Logger{}
+	lc := New(context.Background(), logger)
+
+	var runs int32
+	lc.StartWithRestart("worker", 2, time.Millisecond, func() error {
+		atomic.AddInt32(&runs, 1)
+		panic("boom")
+	})
+
+	if err := lc.Wait(time.Second); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	// The initial run plus two restarts.
+	if got := atomic.LoadInt32(&runs); got != 3 {
+		t.Errorf("fn ran %d times, want 3", got)
+	}
+}
+
+func TestStartWithRestartStopsOnShutdown(t *testing.T) {
+	logger := &mock.Logger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	lc := New(ctx, logger)
+
+	var runs int32
+	lc.StartWithRestart("worker", 100, time.Hour, func() error {
+		atomic.AddInt32(&runs, 1)
+		panic("boom")
+	})
+
+	for atomic.LoadInt32(&runs) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := lc.Wait(time.Second); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("fn ran %d times after shutdown, want 1", got)
+	}
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"golang.org/x/sync/singleflight"
+)
+
+// prepareTimeout bounds statement preparation. Preparation runs on a
+// context detached from the callers so that the statement outlives the
+// request that first needed it.
+const prepareTimeout = 10 * time.Second
+
+// Conn is the handle passed to Do callbacks. Statements are prepared
+// once per query text and cached for the life of the pool.
+type Conn interface {
+	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) (*sql.Row, error)
+	// QueryStructsPrepared scans all rows into dest, a pointer to a
+	// slice of structs, mapping columns onto db-tagged fields like sqlx.
+	QueryStructsPrepared(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	// QueryStructPrepared scans the first row into dest, a pointer to a
+	// struct, returning sql.ErrNoRows when there is none.
+	QueryStructPrepared(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// structMapper maps columns onto struct fields by db tag, matching the
+// sqlx default so both scanning paths agree on field names.
+var structMapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+type dbConn struct {
+	db *sql.DB
+
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+	sf    singleflight.Group
+}
+
+func newDBConn(db *sql.DB) *dbConn {
+	return &dbConn{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// getStmt returns the cached prepared statement for query, preparing it
+// once across concurrent callers via singleflight. The preparation
+// itself is deliberately detached from ctx: the statement is shared
+// state, and preparing under the singleflight leader's context would
+// let that one caller's cancellation fail every waiting follower even
+// though a retry would succeed.
+func (c *dbConn) getStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v, err, _ := c.sf.Do(query, func() (interface{}, error) {
+		prepCtx, cancel := context.WithTimeout(context.Background(), prepareTimeout)
+		defer cancel()
+		stmt, err := c.db.PrepareContext(prepCtx, query)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.stmts[query] = stmt
+		c.mu.Unlock()
+		return stmt, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*sql.Stmt), nil
+}
+
+func (c *dbConn) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := c.getStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (c *dbConn) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := c.getStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (c *dbConn) QueryRow(ctx context.Context, query string, args ...interface{}) (*sql.Row, error) {
+	stmt, err := c.getStmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryRowContext(ctx, args...), nil
+}
+
+func (c *dbConn) QueryStructsPrepared(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close() //nolint:errcheck
+	return sqlx.StructScan(rows, dest)
+}
+
+func (c *dbConn) QueryStructPrepared(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close() //nolint:errcheck
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	xrows := &sqlx.Rows{Rows: rows, Mapper: structMapper}
+	if err := xrows.StructScan(dest); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (c *dbConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.stmts {
+		s.Close() //nolint:errcheck
+	}
+	c.stmts = make(map[string]*sql.Stmt)
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+)
+
+// newLiveDB connects to the shared test database on localhost:2997,
+// skipping when it is unavailable.
+func newLiveDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(Options{
+		Host:       "localhost",
+		Port:       2997,
+		User:       "postgres",
+		Password:   "postgres",
+		DBName:     "postgres",
+		DisableSSL: true,
+	})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	if err := db.DB().Ping(); err != nil {
+		t.Skipf("test database unavailable: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGetStmtSurvivesCallerCancellation(t *testing.T) {
+	db := newLiveDB(t)
+	const query = "SELECT 42"
+
+	// Race a caller's cancellation against the first preparation of the
+	// statement. Whatever that caller sees, the statement cache must not
+	// be poisoned for anyone else.
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if rows, err := db.conn.Query(ctx, query); err == nil {
+			rows.Close()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		cancel()
+	}()
+	wg.Wait()
+
+	rows, err := db.conn.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("expected a working statement after another caller cancelled, got %v", err)
+	}
+	rows.Close()
+
+	if _, err := db.conn.getStmt(context.Background(), query); err != nil {
+		t.Errorf("statement cache poisoned: %v", err)
+	}
+}
+
+func TestGetStmtCancelledCaller(t *testing.T) {
+	db := newLiveDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.conn.getStmt(ctx, "SELECT 1"); err != context.Canceled {
+		t.Fatalf("expected context.Canceled for a cancelled caller, got %v", err)
+	}
+	if _, err := db.conn.getStmt(context.Background(), "SELECT 1"); err != nil {
+		t.Errorf("expected a later caller to get a statement, got %v", err)
+	}
+}
+
+func TestQueryStructsPrepared(t *testing.T) {
+	db := newLiveDB(t)
+
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	var rows []row
+	err := db.conn.QueryStructsPrepared(context.Background(), &rows,
+		"SELECT id, name FROM (VALUES (1, 'a'), (2, 'b')) AS t (id, name) WHERE id >= $1 ORDER BY id", 1)
+	if err != nil {
+		t.Fatalf("QueryStructsPrepared: %v", err)
+	}
+	want := []row{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("rows[%d] = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestQueryStructPrepared(t *testing.T) {
+	db := newLiveDB(t)
+
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	var r row
+	err := db.conn.QueryStructPrepared(context.Background(), &r,
+		"SELECT 7 AS id, $1::text AS name", "seven")
+	if err != nil {
+		t.Fatalf("QueryStructPrepared: %v", err)
+	}
+	if r.ID != 7 || r.Name != "seven" {
+		t.Errorf("row = %+v, want {7 seven}", r)
+	}
+
+	err = db.conn.QueryStructPrepared(context.Background(), &r,
+		"SELECT 7 AS id, 'seven'::text AS name WHERE false")
+	if err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for an empty result, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxParameters is the Postgres limit on bind parameters per statement.
+const maxParameters = 65535
+
+// execer is the minimal execution surface insertInBatches needs; it is
+// satisfied by *sql.Tx and *sql.DB.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// InsertInBatches inserts rows into table in chunks small enough to
+// stay under the Postgres statement parameter limit, all within a
+// single transaction. A chunkSize <= 0 derives the largest safe chunk
+// from the column count.
+func InsertInBatches(ctx context.Context, db *DB, table string, columns []string, rows [][]interface{}, chunkSize int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	if err := insertInBatches(ctx, tx, table, columns, rows, chunkSize); err != nil {
+		tx.Rollback() //nolint:errcheck
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "committing batch insert")
+}
+
+func insertInBatches(ctx context.Context, ex execer, table string, columns []string, rows [][]interface{}, chunkSize int) error {
+	if len(columns) == 0 {
+		return errors.New("postgres: batch insert needs at least one column")
+	}
+	max := maxParameters / len(columns)
+	if chunkSize <= 0 || chunkSize > max {
+		chunkSize = max
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	for start := 0; start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := rows[start:end]
+
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for _, row := range chunk {
+			if len(row) != len(columns) {
+				return errors.Errorf("postgres: row has %d values, want %d", len(row), len(columns))
+			}
+			args = append(args, row...)
+		}
+		query := prefix + NestedPlaceholders(len(chunk), len(columns), 0)
+		if _, err := ex.ExecContext(ctx, query, args...); err != nil {
+			return errors.Wrapf(err, "inserting rows %d-%d into %s", start, end-1, table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+type fakeExecer struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (f *fakeExecer) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, args)
+	return nil, nil
+}
+
+func makeRows(n, cols int) [][]interface{} {
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		row := make([]interface{}, cols)
+		for j := range row {
+			row[j] = fmt.Sprintf("r%dc%d", i, j)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+func TestInsertInBatchesChunking(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		rows        int
+		cols        int
+		chunkSize   int
+		wantBatches []int // rows per statement
+	}{
+		{"exact multiple", 6, 2, 3, []int{3, 3}},
+		{"remainder", 7, 2, 3, []int{3, 3, 1}},
+		{"chunk larger than rows", 2, 2, 10, []int{2}},
+		{"default derives from parameter limit", 3, 2, 0, []int{3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ex := &fakeExecer{}
+			rows := makeRows(tc.rows, tc.cols)
+			if err := insertInBatches(context.Background(), ex, "t", columns(tc.cols), rows, tc.chunkSize); err != nil {
+				t.Fatalf("insertInBatches: %v", err)
+			}
+			if len(ex.queries) != len(tc.wantBatches) {
+				t.Fatalf("expected %d statements, got %d", len(tc.wantBatches), len(ex.queries))
+			}
+			total := 0
+			for i, want := range tc.wantBatches {
+				if got := len(ex.args[i]) / tc.cols; got != want {
+					t.Errorf("statement %d carries %d rows, want %d", i, got, want)
+				}
+				if len(ex.args[i]) > maxParameters {
+					t.Errorf("statement %d has %d parameters, over the limit", i, len(ex.args[i]))
+				}
+				total += len(ex.args[i]) / tc.cols
+			}
+			if total != tc.rows {
+				t.Errorf("%d rows landed, want %d", total, tc.rows)
+			}
+		})
+	}
+}
+
+func TestInsertInBatchesRejectsRaggedRows(t *testing.T) {
+	ex := &fakeExecer{}
+	rows := [][]interface{}{{"a", "b"}, {"only-one"}}
+	if err := insertInBatches(context.Background(), ex, "t", []string{"c1", "c2"}, rows, 10); err == nil {
+		t.Fatal("expected an error for a row with the wrong number of values")
+	}
+}
+
+func columns(n int) []string {
+	cols := make([]string, n)
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d", i)
+	}
+	return cols
+}
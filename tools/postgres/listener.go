@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMinReconnectInterval = 10 * time.Second
+	defaultMaxReconnectInterval = time.Minute
+)
+
+// pqListener is the subset of *pq.Listener the Listener relies on,
+// extracted so tests can substitute a fake.
+type pqListener interface {
+	Listen(channel string) error
+	Unlisten(channel string) error
+	UnlistenAll() error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+type listenerConfig struct {
+	minReconnectInterval time.Duration
+	maxReconnectInterval time.Duration
+	connectionState      func(pq.ListenerEventType, error)
+}
+
+// ListenerOption tunes the behavior of a Listener.
+type ListenerOption func(*listenerConfig)
+
+// WithReconnectIntervals bounds the backoff between reconnection
+// attempts of the underlying pq listener.
+func WithReconnectIntervals(min, max time.Duration) ListenerOption {
+	return func(c *listenerConfig) {
+		c.minReconnectInterval = min
+		c.maxReconnectInterval = max
+	}
+}
+
+// WithConnectionStateFn registers a callback invoked for every
+// connection event (connected, disconnected, reconnected, connection
+// attempt failed) from the underlying pq listener.
+func WithConnectionStateFn(fn func(pq.ListenerEventType, error)) ListenerOption {
+	return func(c *listenerConfig) { c.connectionState = fn }
+}
+
+// listenerDialer adapts net dialing to the pq.Dialer interface.
+type listenerDialer struct{}
+
+func (listenerDialer) Dial(network, address string) (net.Conn, error) {
+	return net.Dial(network, address)
+}
+
+func (listenerDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
+
+// Listener delivers NOTIFY payloads from Postgres channels and keeps
+// its subscriptions alive across reconnects.
+type Listener struct {
+	lr              pqListener
+	connectionState func(pq.ListenerEventType, error)
+
+	mu       sync.Mutex
+	channels map[string]struct{}
+}
+
+// NewListener connects a listener using the given connection options.
+func NewListener(opts Options, los ...ListenerOption) *Listener {
+	cfg := listenerConfig{
+		minReconnectInterval: defaultMinReconnectInterval,
+		maxReconnectInterval: defaultMaxReconnectInterval,
+	}
+	for _, lo := range los {
+		lo(&cfg)
+	}
+	l := &Listener{
+		connectionState: cfg.connectionState,
+		channels:        make(map[string]struct{}),
+	}
+	l.lr = pq.NewDialListener(listenerDialer{}, connStrFromOptions(opts),
+		cfg.minReconnectInterval, cfg.maxReconnectInterval, l.handleEvent)
+	return l
+}
+
+// handleEvent reacts to connection events from pq. Server-side LISTEN
+// registrations do not survive a reconnect, so the subscribed channels
+// are re-listened before the event is surfaced to the callback.
+func (l *Listener) handleEvent(ev pq.ListenerEventType, err error) {
+	if ev == pq.ListenerEventReconnected {
+		l.relisten()
+	}
+	if l.connectionState != nil {
+		l.connectionState(ev, err)
+	}
+}
+
+func (l *Listener) relisten() {
+	for _, ch := range l.subscribed() {
+		l.lr.Listen(ch) //nolint:errcheck // pq retries on the next reconnect
+	}
+}
+
+// subscribed returns a snapshot of the registered channels.
+func (l *Listener) subscribed() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	chs := make([]string, 0, len(l.channels))
+	for ch := range l.channels {
+		chs = append(chs, ch)
+	}
+	return chs
+}
+
+// Listen subscribes to a notification channel. It is idempotent:
+// subscribing to a channel that is already registered is a no-op, so
+// callers need not track which channels they hold.
+func (l *Listener) Listen(channel string) error {
+	l.mu.Lock()
+	if _, ok := l.channels[channel]; ok {
+		l.mu.Unlock()
+		return nil
+	}
+	l.mu.Unlock()
+	if err := l.lr.Listen(channel); err != nil {
+		return errors.Wrapf(err, "listening on %s", channel)
+	}
+	l.mu.Lock()
+	l.channels[channel] = struct{}{}
+	l.mu.Unlock()
+	return nil
+}
+
+// Unlisten drops the subscription to a notification channel. Dropping
+// a channel that was never subscribed is not an error on the server,
+// and the channel simply stops being re-registered after reconnects.
+func (l *Listener) Unlisten(channel string) error {
+	if err := l.lr.Unlisten(channel); err != nil {
+		return errors.Wrapf(err, "unlistening on %s", channel)
+	}
+	l.mu.Lock()
+	delete(l.channels, channel)
+	l.mu.Unlock()
+	return nil
+}
+
+// UnlistenAll drops every subscription.
+func (l *Listener) UnlistenAll() error {
+	if err := l.lr.UnlistenAll(); err != nil {
+		return errors.Wrap(err, "unlistening on all channels")
+	}
+	l.mu.Lock()
+	l.channels = make(map[string]struct{})
+	l.mu.Unlock()
+	return nil
+}
+
+// Channels returns a snapshot of the currently subscribed channels.
+func (l *Listener) Channels() []string {
+	return l.subscribed()
+}
+
+// NotificationChannel returns the channel on which notifications are
+// delivered. It is closed when the listener is closed.
+func (l *Listener) NotificationChannel() <-chan *pq.Notification {
+	return l.lr.NotificationChannel()
+}
+
+// Ping verifies the underlying connection is alive, forcing a reconnect
+// when it is not.
+func (l *Listener) Ping() error {
+	return l.lr.Ping()
+}
+
+// Close tears down the listener and its connection.
+func (l *Listener) Close() error {
+	return l.lr.Close()
+}
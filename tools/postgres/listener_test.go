@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// fakePQListener records Listen calls so tests can drive the event
+// handling without a database.
+type fakePQListener struct {
+	mu        sync.Mutex
+	listens   []string
+	unlistens []string
+}
+
+func (f *fakePQListener) Listen(channel string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listens = append(f.listens, channel)
+	return nil
+}
+
+func (f *fakePQListener) Unlisten(channel string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unlistens = append(f.unlistens, channel)
+	return nil
+}
+
+func (f *fakePQListener) UnlistenAll() error                           { return nil }
+func (f *fakePQListener) NotificationChannel() <-chan *pq.Notification { return nil }
+func (f *fakePQListener) Ping() error                                  { return nil }
+func (f *fakePQListener) Close() error                                 { return nil }
+
+func (f *fakePQListener) listened() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.listens...)
+}
+
+func TestListenerRelistensAfterReconnect(t *testing.T) {
+	fake := &fakePQListener{}
+	var events []pq.ListenerEventType
+	l := &Listener{
+		lr:       fake,
+		channels: make(map[string]struct{}),
+		connectionState: func(ev pq.ListenerEventType, err error) {
+			events = append(events, ev)
+		},
+	}
+
+	for _, ch := range []string{"albums_changed", "photos_changed"} {
+		if err := l.Listen(ch); err != nil {
+			t.Fatalf("listening on %s: %v", ch, err)
+		}
+	}
+
+	l.handleEvent(pq.ListenerEventReconnected, nil)
+
+	got := fake.listened()
+	sort.Strings(got)
+	want := []string{"albums_changed", "albums_changed", "photos_changed", "photos_changed"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d Listen calls, got %d (%v)", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Listen calls = %v, want %v", got, want)
+		}
+	}
+
+	if len(events) != 1 || events[0] != pq.ListenerEventReconnected {
+		t.Errorf("expected the reconnect event to reach the callback, got %v", events)
+	}
+}
+
+func TestListenerChannelManagement(t *testing.T) {
+	fake := &fakePQListener{}
+	l := &Listener{lr: fake, channels: make(map[string]struct{})}
+
+	for _, ch := range []string{"albums_changed", "photos_changed"} {
+		if err := l.Listen(ch); err != nil {
+			t.Fatalf("listening on %s: %v", ch, err)
+		}
+	}
+	got := l.Channels()
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "albums_changed" || got[1] != "photos_changed" {
+		t.Fatalf("Channels = %v, want [albums_changed photos_changed]", got)
+	}
+
+	if err := l.Unlisten("albums_changed"); err != nil {
+		t.Fatalf("unlistening: %v", err)
+	}
+	if got := l.Channels(); len(got) != 1 || got[0] != "photos_changed" {
+		t.Fatalf("Channels after Unlisten = %v, want [photos_changed]", got)
+	}
+	if len(fake.unlistens) != 1 || fake.unlistens[0] != "albums_changed" {
+		t.Errorf("Unlisten calls = %v, want [albums_changed]", fake.unlistens)
+	}
+
+	// An unlistened channel must not be re-registered after a reconnect.
+	fake.listens = nil
+	l.handleEvent(pq.ListenerEventReconnected, nil)
+	if got := fake.listened(); len(got) != 1 || got[0] != "photos_changed" {
+		t.Errorf("relisten calls = %v, want [photos_changed]", got)
+	}
+
+	if err := l.UnlistenAll(); err != nil {
+		t.Fatalf("unlistening all: %v", err)
+	}
+	if got := l.Channels(); len(got) != 0 {
+		t.Errorf("Channels after UnlistenAll = %v, want none", got)
+	}
+}
+
+func TestListenIsIdempotent(t *testing.T) {
+	fake := &fakePQListener{}
+	l := &Listener{lr: fake, channels: make(map[string]struct{})}
+
+	for i := 0; i < 2; i++ {
+		if err := l.Listen("albums_changed"); err != nil {
+			t.Fatalf("Listen call %d: %v", i+1, err)
+		}
+	}
+
+	if got := fake.listened(); len(got) != 1 || got[0] != "albums_changed" {
+		t.Errorf("underlying Listen calls = %v, want exactly one", got)
+	}
+	if got := l.Channels(); len(got) != 1 {
+		t.Errorf("Channels = %v, want the channel registered once", got)
+	}
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Placeholders returns a comma-separated list of n placeholders starting
+// after offset, e.g. Placeholders(3, 0) == "$1,$2,$3".
+func Placeholders(n, offset int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(offset + i + 1))
+	}
+	return b.String()
+}
+
+// InClause renders "column IN ($1,$2,...)" for the given values, with
+// placeholders starting after offset, and returns the values as args
+// ready to append to a query's argument list. An empty slice yields
+// the always-false predicate "FALSE" and no args, since SQL's IN ()
+// is a syntax error.
+func InClause(column string, values []interface{}, offset int) (string, []interface{}) {
+	if len(values) == 0 {
+		return "FALSE", nil
+	}
+	return column + " IN (" + Placeholders(len(values), offset) + ")", values
+}
+
+// NestedPlaceholders returns rows of parenthesised placeholder groups
+// for multi-row inserts, e.g. NestedPlaceholders(2, 2, 0) ==
+// "($1,$2),($3,$4)".
+func NestedPlaceholders(rows, cols, offset int) string {
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		b.WriteString(Placeholders(cols, offset+i*cols))
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import "testing"
+
+func TestInClause(t *testing.T) {
+	t.Run("renders placeholders and args", func(t *testing.T) {
+		frag, args := InClause("id", []interface{}{"a", "b", "c"}, 0)
+		if frag != "id IN ($1,$2,$3)" {
+			t.Errorf("fragment = %q, want id IN ($1,$2,$3)", frag)
+		}
+		if len(args) != 3 || args[0] != "a" || args[2] != "c" {
+			t.Errorf("args = %v, want the values in order", args)
+		}
+	})
+
+	t.Run("offset shifts the placeholders", func(t *testing.T) {
+		frag, _ := InClause("album_id", []interface{}{1, 2}, 3)
+		if frag != "album_id IN ($4,$5)" {
+			t.Errorf("fragment = %q, want album_id IN ($4,$5)", frag)
+		}
+	})
+
+	t.Run("empty values produce a false predicate", func(t *testing.T) {
+		frag, args := InClause("id", nil, 0)
+		if frag != "FALSE" {
+			t.Errorf("fragment = %q, want FALSE", frag)
+		}
+		if len(args) != 0 {
+			t.Errorf("args = %v, want none", args)
+		}
+	})
+}
@@ -0,0 +1,298 @@
+// Package postgres wraps database/sql with connection management,
+// bounded concurrency, per-call timeouts and a prepared-statement cache
+// for PostgreSQL.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq" // postgres driver
+	"github.com/pkg/errors"
+
+	"github.com/devchiran/golang-demo/tools"
+	"github.com/devchiran/golang-demo/tools/requestid"
+	"github.com/devchiran/golang-demo/tools/semaphore"
+)
+
+const (
+	defaultTimeout         = 120 * time.Second
+	defaultMaxOpenConns    = 30
+	defaultMaxIdleConns    = 30
+	defaultMaxConnLifetime = 30 * time.Minute
+	defaultMaxConnIdleTime = 5 * time.Minute
+)
+
+// Options configures the connection pool.
+type Options struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	DBName   string
+	// DisableSSL maps to sslmode=disable. Deprecated: set SSLMode
+	// instead; DisableSSL is kept for backward compatibility and is
+	// ignored when SSLMode is set.
+	DisableSSL bool
+	// SSLMode is one of disable, require, verify-ca or verify-full.
+	// Empty falls back to DisableSSL, and to require when that is
+	// false. Note that require does not verify the server certificate;
+	// prefer verify-full where the infrastructure allows it.
+	SSLMode string
+	// SSLRootCert, SSLCert and SSLKey are optional paths to the CA
+	// certificate and the client certificate pair.
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime recycles connections idle for this long, so the
+	// pool shrinks back between load peaks instead of holding idle
+	// connections for their whole lifetime.
+	MaxConnIdleTime time.Duration
+}
+
+// onCompleteFn observes the outcome of every Do call and may transform
+// the returned error.
+type onCompleteFn func(ctx context.Context, label string, d time.Duration, err error) error
+
+type config struct {
+	timeout         time.Duration
+	sem             *semaphore.Semaphore
+	onComplete      onCompleteFn
+	slowLogger      tools.WarnLoggerCtx
+	slowThreshold   time.Duration
+	maxConnIdleTime time.Duration
+}
+
+// Option tunes the behavior of the DB wrapper.
+type Option func(*config)
+
+// WithTimeout sets the deadline applied to every Do call.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithConnMaxIdleTime overrides Options.MaxConnIdleTime for callers
+// configuring the pool through Options they do not own.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(c *config) { c.maxConnIdleTime = d }
+}
+
+// WithSemaphore bounds how many Do calls may run concurrently. Pass a
+// semaphore instrumented via its WithStats to expose the queue depth
+// and wait time of database calls.
+func WithSemaphore(s *semaphore.Semaphore) Option {
+	return func(c *config) { c.sem = s }
+}
+
+// WithOnComplete registers a hook observing every Do call's outcome. The
+// hook may transform the error returned to the caller.
+func WithOnComplete(fn func(ctx context.Context, label string, d time.Duration, err error) error) Option {
+	return func(c *config) { c.onComplete = fn }
+}
+
+// WithSlowQueryLog warns about Do calls that take at least threshold,
+// tagged with their label and the request id from context. It is
+// independent of, and composes with, WithOnComplete.
+func WithSlowQueryLog(logger tools.LoggerCtx, threshold time.Duration) Option {
+	return func(c *config) {
+		c.slowLogger = logger
+		c.slowThreshold = threshold
+	}
+}
+
+// DB wraps a *sql.DB with bounded concurrency, per-call timeouts and a
+// prepared-statement cache.
+type DB struct {
+	db   *sql.DB
+	conn *dbConn
+	cfg  config
+}
+
+// New opens a connection pool described by opts.
+func New(opts Options, os ...Option) (*DB, error) {
+	cfg := config{
+		timeout: defaultTimeout,
+		sem:     semaphore.New(30, 420),
+	}
+	for _, o := range os {
+		o(&cfg)
+	}
+	db, err := newDB(opts)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.maxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.maxConnIdleTime)
+	}
+	return &DB{db: db, conn: newDBConn(db), cfg: cfg}, nil
+}
+
+func newDB(opts Options) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connStrFromOptions(opts))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening postgres connection")
+	}
+	opts = withPoolDefaults(opts)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.MaxConnLifetime)
+	db.SetConnMaxIdleTime(opts.MaxConnIdleTime)
+	return db, nil
+}
+
+// withPoolDefaults fills the zero pool settings with the package
+// defaults.
+func withPoolDefaults(opts Options) Options {
+	if opts.MaxOpenConns == 0 {
+		opts.MaxOpenConns = defaultMaxOpenConns
+	}
+	if opts.MaxIdleConns == 0 {
+		opts.MaxIdleConns = defaultMaxIdleConns
+	}
+	if opts.MaxConnLifetime == 0 {
+		opts.MaxConnLifetime = defaultMaxConnLifetime
+	}
+	if opts.MaxConnIdleTime == 0 {
+		opts.MaxConnIdleTime = defaultMaxConnIdleTime
+	}
+	return opts
+}
+
+// DB exposes the underlying pool, e.g. for wrapping in sqlx.
+func (d *DB) DB() *sql.DB {
+	return d.db
+}
+
+// Close closes the cached prepared statements and the pool.
+func (d *DB) Close() error {
+	d.conn.close()
+	return d.db.Close()
+}
+
+// Do runs fn with the configured deadline, bounded by the concurrency
+// semaphore, and reports the outcome to the onComplete hook. The label
+// identifies the call site in hooks and logs.
+func (d *DB) Do(ctx context.Context, label string, fn func(ctx context.Context, c Conn) error) error {
+	return d.DoWithTimeout(ctx, label, d.cfg.timeout, fn)
+}
+
+// DoWithTimeout is Do with a per-call deadline overriding the
+// configured one, for operations that need more (or less) time than the
+// rest. A timeout <= 0 disables the deadline entirely; fn is then
+// bounded only by the caller's context.
+func (d *DB) DoWithTimeout(ctx context.Context, label string, timeout time.Duration, fn func(ctx context.Context, c Conn) error) error {
+	if err := d.cfg.sem.Acquire(ctx); err != nil {
+		return errors.Wrap(err, "acquiring db semaphore")
+	}
+	defer d.cfg.sem.Release()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := fn(ctx, d.conn)
+	took := time.Since(start)
+	if d.cfg.slowLogger != nil && took >= d.cfg.slowThreshold {
+		d.cfg.slowLogger.WarnCtx(ctx, "slow query",
+			"label", label,
+			"duration", took.String(),
+			"request_id", requestid.Get(ctx),
+		)
+	}
+	if d.cfg.onComplete != nil {
+		err = d.cfg.onComplete(ctx, label, took, err)
+	}
+	return err
+}
+
+func sslMode(opts Options) string {
+	if opts.SSLMode != "" {
+		return opts.SSLMode
+	}
+	if opts.DisableSSL {
+		return "disable"
+	}
+	return "require"
+}
+
+// sslParams renders the sslmode and optional certificate paths as
+// key=value pairs.
+func sslParams(opts Options) []string {
+	params := []string{"sslmode=" + sslMode(opts)}
+	if opts.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+opts.SSLRootCert)
+	}
+	if opts.SSLCert != "" {
+		params = append(params, "sslcert="+opts.SSLCert)
+	}
+	if opts.SSLKey != "" {
+		params = append(params, "sslkey="+opts.SSLKey)
+	}
+	return params
+}
+
+// connStrFromOptions renders opts as a key/value connection string.
+func connStrFromOptions(opts Options) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s %s",
+		opts.Host, opts.Port, opts.User, opts.Password, opts.DBName,
+		strings.Join(sslParams(opts), " "))
+}
+
+// ParseURL builds Options from a postgres:// URL such as the
+// DATABASE_URL handed out by hosting platforms. Percent-encoded user
+// info is decoded, an omitted port falls back to 5432, and the sslmode
+// query parameter is carried into Options.SSLMode.
+func ParseURL(dsn string) (Options, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return Options{}, errors.Wrap(err, "parsing database url")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return Options{}, errors.Errorf("unsupported scheme %q in database url", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return Options{}, errors.New("database url has no host")
+	}
+	opts := Options{
+		Host:   u.Hostname(),
+		Port:   5432,
+		DBName: strings.TrimPrefix(u.Path, "/"),
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return Options{}, errors.Wrapf(err, "parsing port %q in database url", p)
+		}
+		opts.Port = port
+	}
+	if u.User != nil {
+		opts.User = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	opts.SSLMode = u.Query().Get("sslmode")
+	return opts, nil
+}
+
+// urlFromOptions renders opts as a postgres:// URL.
+func urlFromOptions(opts Options) string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(opts.User, opts.Password),
+		Host:     fmt.Sprintf("%s:%d", opts.Host, opts.Port),
+		Path:     "/" + opts.DBName,
+		RawQuery: strings.Join(sslParams(opts), "&"),
+	}
+	return u.String()
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Typed sentinels for the constraint-violation SQLSTATE classes, so
+// callers can branch with errors.Is instead of inspecting pq internals.
+var (
+	// ErrUniqueViolation maps SQLSTATE 23505.
+	ErrUniqueViolation = errors.New("postgres: unique violation")
+	// ErrForeignKeyViolation maps SQLSTATE 23503.
+	ErrForeignKeyViolation = errors.New("postgres: foreign key violation")
+	// ErrCheckViolation maps SQLSTATE 23514.
+	ErrCheckViolation = errors.New("postgres: check constraint violation")
+)
+
+// Typed sentinels for driver-level failures that are usually worth
+// retrying, mapped by ErrorClassifyingOnComplete.
+var (
+	// ErrTimeout marks Do calls that ran out of deadline.
+	ErrTimeout = errors.New("postgres: operation timed out")
+	// ErrConnDone marks operations that hit an already-released
+	// connection, sql.ErrConnDone in the driver.
+	ErrConnDone = errors.New("postgres: connection done")
+)
+
+// AsPQError unwraps err to the underlying *pq.Error, if any.
+func AsPQError(err error) (*pq.Error, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr, true
+	}
+	return nil, false
+}
+
+// Classify maps a constraint-violation error onto the matching typed
+// sentinel, annotated with the violated constraint. Errors that are not
+// pq constraint violations are returned unchanged.
+func Classify(err error) error {
+	pqErr, ok := AsPQError(err)
+	if !ok {
+		return err
+	}
+	var sentinel error
+	switch pqErr.Code {
+	case "23505":
+		sentinel = ErrUniqueViolation
+	case "23503":
+		sentinel = ErrForeignKeyViolation
+	case "23514":
+		sentinel = ErrCheckViolation
+	default:
+		return err
+	}
+	if pqErr.Constraint != "" {
+		return errors.Wrapf(sentinel, "constraint %s", pqErr.Constraint)
+	}
+	return errors.Wrap(sentinel, pqErr.Message)
+}
+
+// ErrorClassifyingOnComplete builds a WithOnComplete hook that maps
+// low-level failures onto the package's typed sentinels — deadline
+// exhaustion to ErrTimeout, sql.ErrConnDone to ErrConnDone, constraint
+// violations through Classify — so every Do caller sees consistent
+// error types from one place. next runs first and may transform the
+// error before classification; pass nil when no other hook is needed.
+func ErrorClassifyingOnComplete(next onCompleteFn) onCompleteFn {
+	return func(ctx context.Context, label string, d time.Duration, err error) error {
+		if next != nil {
+			err = next(ctx, label, d, err)
+		}
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, context.DeadlineExceeded):
+			return errors.Wrap(ErrTimeout, label)
+		case errors.Is(err, sql.ErrConnDone):
+			return errors.Wrap(ErrConnDone, label)
+		default:
+			return Classify(err)
+		}
+	}
+}
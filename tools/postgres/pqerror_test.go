@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "unique violation",
+			err:  &pq.Error{Code: "23505", Constraint: "albums_title_key"},
+			want: ErrUniqueViolation,
+		},
+		{
+			name: "foreign key violation",
+			err:  &pq.Error{Code: "23503", Constraint: "photos_album_id_fkey"},
+			want: ErrForeignKeyViolation,
+		},
+		{
+			name: "check violation",
+			err:  &pq.Error{Code: "23514", Constraint: "albums_title_check"},
+			want: ErrCheckViolation,
+		},
+		{
+			name: "wrapped pq error is still classified",
+			err:  errors.Wrap(&pq.Error{Code: "23505"}, "creating album"),
+			want: ErrUniqueViolation,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Classify(tc.err)
+			if !errors.Is(got, tc.want) {
+				t.Errorf("Classify(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClassifyPassesThroughOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		errors.New("plain error"),
+		&pq.Error{Code: "40001"}, // serialization failure, not a constraint
+		nil,
+	} {
+		if got := Classify(err); got != err { //nolint:errorlint
+			t.Errorf("Classify(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
+
+func TestAsPQError(t *testing.T) {
+	pqErr := &pq.Error{Code: "23505"}
+	if got, ok := AsPQError(errors.Wrap(pqErr, "wrapped")); !ok || got != pqErr {
+		t.Errorf("AsPQError = %v (%v), want the original pq error", got, ok)
+	}
+	if _, ok := AsPQError(errors.New("other")); ok {
+		t.Error("expected no pq error")
+	}
+}
+
+func TestErrorClassifyingOnComplete(t *testing.T) {
+	hook := ErrorClassifyingOnComplete(nil)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "deadline exceeded",
+			err:  errors.Wrap(context.DeadlineExceeded, "querying"),
+			want: ErrTimeout,
+		},
+		{
+			name: "connection done",
+			err:  sql.ErrConnDone,
+			want: ErrConnDone,
+		},
+		{
+			name: "constraint violations still classify",
+			err:  &pq.Error{Code: "23505", Constraint: "albums_pkey"},
+			want: ErrUniqueViolation,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := hook(ctx, "get_album", time.Millisecond, tc.err)
+			if !errors.Is(got, tc.want) {
+				t.Errorf("hook(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+
+	if got := hook(ctx, "get_album", time.Millisecond, nil); got != nil {
+		t.Errorf("hook(nil) = %v, want nil", got)
+	}
+
+	t.Run("wrapped hook runs first", func(t *testing.T) {
+		inner := ErrorClassifyingOnComplete(func(_ context.Context, _ string, _ time.Duration, err error) error {
+			return errors.Wrap(err, "observed")
+		})
+		got := inner(ctx, "get_album", time.Millisecond, context.DeadlineExceeded)
+		if !errors.Is(got, ErrTimeout) {
+			t.Errorf("composed hook = %v, want ErrTimeout", got)
+		}
+	})
+}
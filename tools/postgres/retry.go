@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/devchiran/golang-demo/tools/backoff"
+)
+
+// retryBackoff paces retries of transient failures; a handful of
+// milliseconds is usually enough for the conflicting transaction to
+// finish.
+var retryBackoff = backoff.Exponential{Base: 50 * time.Millisecond, Max: time.Second, Jitter: 0.5}
+
+// isTransient reports whether err is a serialization failure or
+// deadlock, the two classes postgres documents as safe to retry by
+// re-running the whole transaction.
+func isTransient(err error) bool {
+	pqErr, ok := errors.Cause(err).(*pq.Error)
+	if !ok {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
+
+// DoWithRetry is Do, re-running fn up to maxAttempts times when it
+// fails with a transient error (serialization failure or deadlock).
+// Other errors, and the last transient error once attempts are
+// exhausted, are returned as-is. fn must be safe to re-run from
+// scratch, e.g. a whole BeginTx transaction.
+func (d *DB) DoWithRetry(ctx context.Context, label string, maxAttempts int, fn func(ctx context.Context, c Conn) error) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if werr := retryBackoff.Wait(ctx, attempt-1); werr != nil {
+				return err
+			}
+		}
+		err = d.Do(ctx, label, fn)
+		if err == nil || !isTransient(err) {
+			return err
+		}
+	}
+	return err
+}
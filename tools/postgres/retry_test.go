@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// newRetryDB opens a pool without connecting; the retry tests never
+// touch the database.
+func newRetryDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(Options{Host: "localhost", Port: 5432, User: "x", Password: "x", DBName: "x"})
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDoWithRetryTransient(t *testing.T) {
+	db := newRetryDB(t)
+
+	calls := 0
+	err := db.DoWithRetry(context.Background(), "test", 5, func(context.Context, Conn) error {
+		calls++
+		if calls < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestDoWithRetryWrappedTransient(t *testing.T) {
+	db := newRetryDB(t)
+
+	calls := 0
+	err := db.DoWithRetry(context.Background(), "test", 2, func(context.Context, Conn) error {
+		calls++
+		return errors.Wrap(&pq.Error{Code: "40P01"}, "running tx")
+	})
+	if err == nil {
+		t.Fatal("expected the last error once attempts are exhausted")
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestDoWithRetryNonTransient(t *testing.T) {
+	db := newRetryDB(t)
+
+	boom := errors.New("boom")
+	calls := 0
+	err := db.DoWithRetry(context.Background(), "test", 5, func(context.Context, Conn) error {
+		calls++
+		return boom
+	})
+	if errors.Cause(err) != boom {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+
+	// Constraint violations are not transient either.
+	calls = 0
+	err = db.DoWithRetry(context.Background(), "test", 5, func(context.Context, Conn) error {
+		calls++
+		return &pq.Error{Code: "23505"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("unique violation retried: err=%v calls=%d", err, calls)
+	}
+}
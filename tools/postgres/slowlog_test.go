@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/mock"
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+func TestWithSlowQueryLog(t *testing.T) {
+	logger := &mock.Logger{}
+	db, err := New(
+		Options{Host: "localhost", Port: 5432, User: "x", Password: "x", DBName: "x"},
+		WithSlowQueryLog(logger, 20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := requestid.Set(context.Background(), "req-42")
+
+	if err := db.Do(ctx, "fast_query", func(context.Context, Conn) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("fast query: %v", err)
+	}
+	if got := logger.Entries(); len(got) != 0 {
+		t.Fatalf("fast query logged %d entries, want none", len(got))
+	}
+
+	if err := db.Do(ctx, "slow_query", func(context.Context, Conn) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}); err != nil {
+		t.Fatalf("slow query: %v", err)
+	}
+	entries := logger.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("slow query logged %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Level != "warn" || e.Msg != "slow query" {
+		t.Errorf("logged %s %q, want warn \"slow query\"", e.Level, e.Msg)
+	}
+	kv := map[interface{}]interface{}{}
+	for i := 0; i+1 < len(e.KeyVals); i += 2 {
+		kv[e.KeyVals[i]] = e.KeyVals[i+1]
+	}
+	if kv["label"] != "slow_query" {
+		t.Errorf("label = %v, want slow_query", kv["label"])
+	}
+	if kv["request_id"] != "req-42" {
+		t.Errorf("request_id = %v, want req-42", kv["request_id"])
+	}
+}
+
+func TestWithSlowQueryLogComposesWithOnComplete(t *testing.T) {
+	logger := &mock.Logger{}
+	var observed []string
+	db, err := New(
+		Options{Host: "localhost", Port: 5432, User: "x", Password: "x", DBName: "x"},
+		WithSlowQueryLog(logger, time.Millisecond),
+		WithOnComplete(func(_ context.Context, label string, _ time.Duration, err error) error {
+			observed = append(observed, label)
+			return err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Do(context.Background(), "both_hooks", func(context.Context, Conn) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(observed) != 1 || observed[0] != "both_hooks" {
+		t.Errorf("onComplete observed %v, want [both_hooks]", observed)
+	}
+	if len(logger.Entries()) != 1 {
+		t.Errorf("slow log recorded %d entries, want 1", len(logger.Entries()))
+	}
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// StatsOnComplete returns a WithOnComplete hook recording RED metrics
+// for every Do call: a count under prefix+".queries" tagged with the
+// call label and ok|error, and a duration histogram under
+// prefix+".duration_seconds" tagged with the label. The error is passed
+// through untouched. A nil stats client records nothing.
+func StatsOnComplete(sc tools.StatsClient, prefix string) func(ctx context.Context, label string, d time.Duration, err error) error {
+	sc = tools.OrNop(sc)
+	return func(_ context.Context, label string, d time.Duration, err error) error {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		sc.Count(prefix+".queries", 1, "label:"+label, "outcome:"+outcome)
+		sc.Histogram(prefix+".duration_seconds", d.Seconds(), "label:"+label)
+		return err
+	}
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recordedMetric captures one stats call with its tags.
+type recordedMetric struct {
+	name  string
+	value float64
+	tags  []string
+}
+
+// recordingStats collects every Count and Histogram call.
+type recordingStats struct {
+	counts     []recordedMetric
+	histograms []recordedMetric
+}
+
+func (s *recordingStats) Count(name string, value float64, tags ...string) {
+	s.counts = append(s.counts, recordedMetric{name, value, tags})
+}
+
+func (s *recordingStats) Histogram(name string, value float64, tags ...string) {
+	s.histograms = append(s.histograms, recordedMetric{name, value, tags})
+}
+
+func (s *recordingStats) Gauge(string, float64, ...string) {}
+func (s *recordingStats) Handler() http.Handler            { return nil }
+
+func hasTag(m recordedMetric, want string) bool {
+	for _, tag := range m.tags {
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsOnComplete(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sc := &recordingStats{}
+		hook := StatsOnComplete(sc, "postgres")
+
+		if err := hook(context.Background(), "get_album", 25*time.Millisecond, nil); err != nil {
+			t.Fatalf("expected a nil error to pass through, got %v", err)
+		}
+		if len(sc.counts) != 1 || sc.counts[0].name != "postgres.queries" {
+			t.Fatalf("counts = %v, want one postgres.queries", sc.counts)
+		}
+		if !hasTag(sc.counts[0], "label:get_album") || !hasTag(sc.counts[0], "outcome:ok") {
+			t.Errorf("count tags = %v, want label:get_album and outcome:ok", sc.counts[0].tags)
+		}
+		if len(sc.histograms) != 1 || sc.histograms[0].name != "postgres.duration_seconds" {
+			t.Fatalf("histograms = %v, want one postgres.duration_seconds", sc.histograms)
+		}
+		if sc.histograms[0].value != 0.025 {
+			t.Errorf("duration = %v, want 0.025", sc.histograms[0].value)
+		}
+		if !hasTag(sc.histograms[0], "label:get_album") {
+			t.Errorf("histogram tags = %v, want label:get_album", sc.histograms[0].tags)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		sc := &recordingStats{}
+		hook := StatsOnComplete(sc, "postgres")
+
+		failure := errors.New("connection reset")
+		if err := hook(context.Background(), "create_album", time.Millisecond, failure); err != failure {
+			t.Fatalf("expected the error to pass through, got %v", err)
+		}
+		if len(sc.counts) != 1 || !hasTag(sc.counts[0], "outcome:error") {
+			t.Errorf("counts = %v, want one tagged outcome:error", sc.counts)
+		}
+		if !hasTag(sc.counts[0], "label:create_album") {
+			t.Errorf("count tags = %v, want label:create_album", sc.counts[0].tags)
+		}
+	})
+}
+
+func TestStatsOnCompleteNilClient(t *testing.T) {
+	hook := StatsOnComplete(nil, "postgres")
+
+	if err := hook(context.Background(), "get_album", time.Millisecond, nil); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	failure := errors.New("boom")
+	if err := hook(context.Background(), "get_album", time.Millisecond, failure); err != failure {
+		t.Fatalf("expected the error to pass through, got %v", err)
+	}
+}
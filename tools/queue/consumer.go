@@ -0,0 +1,347 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools"
+	"github.com/devchiran/golang-demo/tools/clock"
+	"github.com/devchiran/golang-demo/tools/semaphore"
+)
+
+const (
+	defaultNumWorkers        = 10
+	defaultWaitTime          = 20 * time.Second
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultDrainTimeout      = 30 * time.Second
+	defaultExtendThreshold   = 0.5
+	defaultExpiryThreshold   = 0.9
+)
+
+// ConsumerOption tunes a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithNumWorkers sets how many messages are handled concurrently.
+func WithNumWorkers(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.numWorkers = n
+		}
+	}
+}
+
+// WithMessageCount sets how many messages are fetched per poll,
+// independently of the worker count. Defaults to the worker count.
+func WithMessageCount(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.messageCount = n
+		}
+	}
+}
+
+// WithWaitTime sets the long-poll wait passed to the backend.
+func WithWaitTime(d time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.waitTime = d }
+}
+
+// WithVisibilityTimeout sets how long fetched messages stay invisible
+// to other consumers.
+func WithVisibilityTimeout(d time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.visibilityTimeout = d }
+}
+
+// WithDrainTimeout sets how long Consume waits for in-flight handlers
+// to finish (and ack) after its context is cancelled, before their
+// contexts are cancelled too.
+func WithDrainTimeout(d time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if d > 0 {
+			c.drainTimeout = d
+		}
+	}
+}
+
+// WithExtendThreshold sets the fraction of the visibility timeout after
+// which a message's visibility is first extended. Values outside (0, 1)
+// are ignored.
+func WithExtendThreshold(pct float64) ConsumerOption {
+	return func(c *Consumer) {
+		if pct > 0 && pct < 1 {
+			c.extendThreshold = pct
+		}
+	}
+}
+
+// WithExpiryThreshold sets the fraction of the visibility timeout after
+// which in-flight work on a message is abandoned. Values outside (0, 1)
+// are ignored.
+func WithExpiryThreshold(pct float64) ConsumerOption {
+	return func(c *Consumer) {
+		if pct > 0 && pct < 1 {
+			c.expiryThreshold = pct
+		}
+	}
+}
+
+// WithMaxInFlight bounds how many fetched messages may be held at once,
+// across polling and handling. It caps memory for message bodies
+// independently of the worker count; without it, up to a full batch per
+// poll can pile up behind busy workers.
+func WithMaxInFlight(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.maxInFlight = n
+		}
+	}
+}
+
+// WithPanicBudget makes Consume give up once handler panics exceed max
+// within the sliding window. Per-message recovery keeps one bad message
+// from killing a worker, but a handler that panics on everything would
+// spin forever re-fetching and panicking; tripping the budget turns
+// that into a fatal ErrPanicBudgetExceeded so the caller's lifecycle
+// can restart or alert. Non-positive values disable the budget.
+func WithPanicBudget(max int, window time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if max > 0 && window > 0 {
+			c.panicBudget = max
+			c.panicWindow = window
+		}
+	}
+}
+
+// WithClock substitutes the clock, for tests.
+func WithClock(cl clock.Clock) ConsumerOption {
+	return func(c *Consumer) { c.clock = cl }
+}
+
+// WithLogger sets the logger used for handler and queue errors.
+func WithLogger(l tools.Logger) ConsumerOption {
+	return func(c *Consumer) { c.logger = l }
+}
+
+// WithContextExtractor registers fn to derive each message's handler
+// context, typically pulling propagated trace context (e.g. a W3C
+// traceparent) out of the message. fn receives the consumer's
+// per-message base context and must return a context derived from it,
+// so cancellation during drain still reaches the handler.
+func WithContextExtractor(fn func(ctx context.Context, m Message) context.Context) ConsumerOption {
+	return func(c *Consumer) { c.extractContext = fn }
+}
+
+// Consumer fetches messages and dispatches them to a pool of workers.
+type Consumer struct {
+	queue             Queue
+	numWorkers        int
+	messageCount      int
+	waitTime          time.Duration
+	visibilityTimeout time.Duration
+	drainTimeout      time.Duration
+	extendThreshold   float64
+	expiryThreshold   float64
+	maxInFlight       int
+	panicBudget       int
+	panicWindow       time.Duration
+	sem               *semaphore.Semaphore
+	panics            *panicTracker
+	clock             clock.Clock
+	logger            tools.Logger
+	extractContext    func(ctx context.Context, m Message) context.Context
+}
+
+// NewConsumer builds a Consumer reading from q.
+func NewConsumer(q Queue, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		queue:             q,
+		numWorkers:        defaultNumWorkers,
+		waitTime:          defaultWaitTime,
+		visibilityTimeout: defaultVisibilityTimeout,
+		drainTimeout:      defaultDrainTimeout,
+		extendThreshold:   defaultExtendThreshold,
+		expiryThreshold:   defaultExpiryThreshold,
+		clock:             clock.New(),
+		logger:            nopLogger{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.messageCount <= 0 {
+		c.messageCount = c.numWorkers
+	}
+	// Extending after work would already have been abandoned makes no
+	// sense; fall back to the defaults in that case.
+	if c.extendThreshold >= c.expiryThreshold {
+		c.extendThreshold = defaultExtendThreshold
+		c.expiryThreshold = defaultExpiryThreshold
+	}
+	if c.maxInFlight > 0 {
+		// Only the poll loop acquires, so the queue bound never bites.
+		c.sem = semaphore.New(int64(c.maxInFlight), int64(c.maxInFlight))
+	}
+	if c.panicBudget > 0 {
+		c.panics = newPanicTracker(c.panicBudget, c.panicWindow, c.clock)
+	}
+	return c
+}
+
+// Consume fetches and processes messages until ctx is cancelled. Once
+// cancelled, it stops fetching and waits up to the drain timeout for
+// in-flight handlers to finish acking before returning. With a panic
+// budget configured it also returns, with ErrPanicBudgetExceeded, when
+// handler panics trip the budget.
+func (c *Consumer) Consume(ctx context.Context, h Handler) error {
+	if c.panics != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-c.panics.tripped:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	// msgCtx outlives ctx by up to the drain timeout so in-flight
+	// handlers can finish during shutdown.
+	msgCtx, msgCancel := context.WithCancel(context.Background())
+	defer msgCancel()
+
+	msgs := make(chan *pollMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < c.numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.worker(msgs, h)
+		}()
+	}
+
+	err := c.poll(ctx, msgCtx, msgs)
+	close(msgs)
+	if c.panics != nil && c.panics.isTripped() {
+		err = ErrPanicBudgetExceeded
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	drained := make(chan struct{})
+	dt := c.clock.AfterFunc(c.drainTimeout, func() { close(drained) })
+	defer dt.Stop()
+	select {
+	case <-done:
+	case <-drained:
+		msgCancel()
+		<-done
+	}
+	return err
+}
+
+func (c *Consumer) worker(msgs <-chan *pollMessage, h Handler) {
+	for pm := range msgs {
+		pm.process(h)
+		c.releaseSlots(1)
+	}
+}
+
+func (c *Consumer) poll(ctx, msgCtx context.Context, msgs chan<- *pollMessage) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		count, err := c.acquireSlots(ctx)
+		if err != nil {
+			return err
+		}
+		batch, err := c.getMessages(ctx, count)
+		if err != nil {
+			c.releaseSlots(count)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Error("fetching messages", "error", err.Error())
+			continue
+		}
+		// Slots for the unfilled remainder of the batch go straight
+		// back; the rest are released by the workers as handling ends.
+		c.releaseSlots(count - len(batch))
+		pms := c.pollMessages(msgCtx, batch)
+		for i, pm := range pms {
+			select {
+			case msgs <- pm:
+			case <-ctx.Done():
+				c.releaseSlots(len(pms) - i)
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// acquireSlots reserves in-flight slots for the next fetch: it blocks
+// until at least one is free, then grabs up to a full batch's worth
+// without waiting. Without a max-in-flight bound it reports the full
+// batch size immediately.
+func (c *Consumer) acquireSlots(ctx context.Context) (int, error) {
+	if c.sem == nil {
+		return c.messageCount, nil
+	}
+	if err := c.sem.Acquire(ctx); err != nil {
+		return 0, err
+	}
+	n := 1
+	for n < c.messageCount && c.sem.TryAcquire() {
+		n++
+	}
+	return n, nil
+}
+
+func (c *Consumer) releaseSlots(n int) {
+	if c.sem == nil || n <= 0 {
+		return
+	}
+	c.sem.ReleaseN(int64(n))
+}
+
+func (c *Consumer) getMessages(ctx context.Context, count int) ([]Message, error) {
+	return c.queue.GetMessages(ctx, GetMessagesRequest{
+		MessageCount:      count,
+		WaitTime:          c.waitTime,
+		VisibilityTimeout: c.visibilityTimeout,
+	})
+}
+
+// pollMessages prepares a fetched batch for dispatch to the workers.
+// Each message's context descends from the shutdown-aware msgCtx so a
+// drain can bound in-flight work.
+func (c *Consumer) pollMessages(msgCtx context.Context, batch []Message) []*pollMessage {
+	pms := make([]*pollMessage, 0, len(batch))
+	for _, m := range batch {
+		ctx := msgCtx
+		if c.extractContext != nil {
+			ctx = c.extractContext(msgCtx, m)
+		}
+		pm := &pollMessage{
+			ctx:               ctx,
+			msg:               m,
+			queue:             c.queue,
+			logger:            c.logger,
+			visibilityTimeout: c.visibilityTimeout,
+			extendThreshold:   c.extendThreshold,
+			expiryThreshold:   c.expiryThreshold,
+			clock:             c.clock,
+		}
+		if c.panics != nil {
+			pm.onPanic = c.panics.record
+		}
+		pms = append(pms, pm)
+	}
+	return pms
+}
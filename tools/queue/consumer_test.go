@@ -0,0 +1,271 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/mock"
+	"github.com/devchiran/golang-demo/tools/queue"
+)
+
+func TestConsumerDrainsInFlightHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fetched := false
+	started := make(chan struct{})
+	acked := make(chan string, 1)
+	q := &mock.Queue{
+		GetMessagesFn: func(ctx context.Context, _ queue.GetMessagesRequest) ([]queue.Message, error) {
+			if fetched {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			fetched = true
+			return []queue.Message{{ID: "m1"}}, nil
+		},
+		AckFn: func(_ context.Context, m queue.Message) error {
+			acked <- m.ID
+			return nil
+		},
+	}
+
+	c := queue.NewConsumer(q,
+		queue.WithNumWorkers(1),
+		queue.WithDrainTimeout(2*time.Second),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, queue.HandlerFunc(func(ctx context.Context, m queue.Message) error {
+			close(started)
+			select {
+			case <-time.After(50 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}))
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Consume did not return within the drain window")
+	}
+	select {
+	case id := <-acked:
+		if id != "m1" {
+			t.Errorf("acked %q, want m1", id)
+		}
+	default:
+		t.Error("expected the in-flight message to be acked during drain")
+	}
+}
+
+func TestConsumerMessageCount(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []queue.ConsumerOption
+		want int
+	}{
+		{
+			name: "configured count is used",
+			opts: []queue.ConsumerOption{queue.WithNumWorkers(2), queue.WithMessageCount(7)},
+			want: 7,
+		},
+		{
+			name: "defaults to the worker count",
+			opts: []queue.ConsumerOption{queue.WithNumWorkers(4)},
+			want: 4,
+		},
+		{
+			name: "non-positive count falls back to the worker count",
+			opts: []queue.ConsumerOption{queue.WithNumWorkers(3), queue.WithMessageCount(0)},
+			want: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			var got int
+			q := &mock.Queue{
+				GetMessagesFn: func(_ context.Context, req queue.GetMessagesRequest) ([]queue.Message, error) {
+					got = req.MessageCount
+					cancel()
+					return nil, nil
+				},
+			}
+
+			c := queue.NewConsumer(q, tc.opts...)
+			if err := c.Consume(ctx, queue.HandlerFunc(func(context.Context, queue.Message) error {
+				return nil
+			})); err != context.Canceled {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("MessageCount = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConsumerMaxInFlight(t *testing.T) {
+	const (
+		maxInFlight = 3
+		total       = 12
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	outstanding, peak, delivered := 0, 0, 0
+	q := &mock.Queue{
+		GetMessagesFn: func(ctx context.Context, req queue.GetMessagesRequest) ([]queue.Message, error) {
+			mu.Lock()
+			if req.MessageCount > maxInFlight {
+				t.Errorf("fetch asked for %d messages, more than the %d in-flight bound", req.MessageCount, maxInFlight)
+			}
+			if delivered >= total {
+				mu.Unlock()
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			n := req.MessageCount
+			if n > total-delivered {
+				n = total - delivered
+			}
+			msgs := make([]queue.Message, n)
+			for i := range msgs {
+				msgs[i] = queue.Message{ID: fmt.Sprintf("m%d", delivered+i)}
+			}
+			delivered += n
+			outstanding += n
+			if outstanding > peak {
+				peak = outstanding
+			}
+			mu.Unlock()
+			return msgs, nil
+		},
+	}
+
+	handled := make(chan struct{}, total)
+	c := queue.NewConsumer(q,
+		queue.WithNumWorkers(6),
+		queue.WithMessageCount(6),
+		queue.WithMaxInFlight(maxInFlight),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, queue.HandlerFunc(func(context.Context, queue.Message) error {
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			outstanding--
+			mu.Unlock()
+			handled <- struct{}{}
+			return nil
+		}))
+	}()
+
+	for i := 0; i < total; i++ {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d messages were handled", i, total)
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak > maxInFlight {
+		t.Errorf("held %d messages at once, want at most %d", peak, maxInFlight)
+	}
+	if peak == 0 {
+		t.Error("expected some messages to be fetched")
+	}
+}
+
+func TestConsumerContextExtractor(t *testing.T) {
+	type traceKey struct{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetched := false
+	q := &mock.Queue{
+		GetMessagesFn: func(ctx context.Context, _ queue.GetMessagesRequest) ([]queue.Message, error) {
+			if fetched {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			fetched = true
+			return []queue.Message{{ID: "m1", Body: []byte("traceparent:00-abc")}}, nil
+		},
+		AckFn: func(context.Context, queue.Message) error { return nil },
+	}
+
+	c := queue.NewConsumer(q,
+		queue.WithNumWorkers(1),
+		queue.WithContextExtractor(func(ctx context.Context, m queue.Message) context.Context {
+			return context.WithValue(ctx, traceKey{}, string(m.Body))
+		}),
+	)
+
+	seen := make(chan interface{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Consume(ctx, queue.HandlerFunc(func(ctx context.Context, _ queue.Message) error {
+			seen <- ctx.Value(traceKey{})
+			return nil
+		}))
+	}()
+
+	select {
+	case v := <-seen:
+		if v != "traceparent:00-abc" {
+			t.Errorf("handler context carries %v, want the extracted trace value", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler was not invoked")
+	}
+	cancel()
+	<-done
+}
+
+func TestConsumerPanicBudget(t *testing.T) {
+	fetched := 0
+	q := &mock.Queue{
+		GetMessagesFn: func(ctx context.Context, _ queue.GetMessagesRequest) ([]queue.Message, error) {
+			fetched++
+			return []queue.Message{{ID: fmt.Sprintf("m%d", fetched)}}, nil
+		},
+	}
+	c := queue.NewConsumer(q,
+		queue.WithNumWorkers(2),
+		queue.WithPanicBudget(3, time.Minute),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.Consume(ctx, queue.HandlerFunc(func(context.Context, queue.Message) error {
+		panic("pathological handler")
+	}))
+
+	if !errors.Is(err, queue.ErrPanicBudgetExceeded) {
+		t.Fatalf("Consume = %v, want ErrPanicBudgetExceeded", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Consume only returned once the test deadline cancelled it, want the budget to trip it")
+	}
+}
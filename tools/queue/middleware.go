@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// Middleware wraps a Handler with cross-cutting behavior.
+type Middleware func(Handler) Handler
+
+// Chain applies mws to h so that the first middleware is the outermost,
+// i.e. Chain(h, a, b) handles a message as a(b(h)).
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// LoggingHandler logs every handled message with its outcome and
+// duration.
+func LoggingHandler(logger tools.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, m Message) error {
+			start := time.Now()
+			err := next.Handle(ctx, m)
+			if err != nil {
+				logger.Error("message handled",
+					"id", m.ID, "attempts", m.Attempts,
+					"duration", time.Since(start).String(), "error", err.Error())
+				return err
+			}
+			logger.Info("message handled",
+				"id", m.ID, "attempts", m.Attempts,
+				"duration", time.Since(start).String())
+			return nil
+		})
+	}
+}
+
+// TimeoutHandler bounds each message's handling time with a context
+// deadline derived from the per-message context.
+func TimeoutHandler(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, m Message) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.Handle(ctx, m)
+		})
+	}
+}
+
+// StatsHandler records handling duration and the ack/no-ack outcome
+// under name.
+func StatsHandler(sc tools.StatsClient, name string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, m Message) error {
+			start := time.Now()
+			err := next.Handle(ctx, m)
+			outcome := "ack"
+			if err != nil {
+				outcome = "noack"
+			}
+			sc.Count(name+".messages", 1, "outcome:"+outcome)
+			sc.Histogram(name+".handle_duration_seconds", time.Since(start).Seconds(), "outcome:"+outcome)
+			return err
+		})
+	}
+}
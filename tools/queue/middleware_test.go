@@ -0,0 +1,55 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools/queue"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	record := func(name string) queue.Middleware {
+		return func(next queue.Handler) queue.Handler {
+			return queue.HandlerFunc(func(ctx context.Context, m queue.Message) error {
+				order = append(order, name)
+				return next.Handle(ctx, m)
+			})
+		}
+	}
+
+	h := queue.Chain(queue.HandlerFunc(func(context.Context, queue.Message) error {
+		order = append(order, "handler")
+		return nil
+	}), record("outer"), record("inner"))
+
+	if err := h.Handle(context.Background(), queue.Message{}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	want := []string{"outer", "inner", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("call order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("call order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestTimeoutHandlerCancelsContext(t *testing.T) {
+	h := queue.Chain(queue.HandlerFunc(func(ctx context.Context, m queue.Message) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}), queue.TimeoutHandler(10*time.Millisecond))
+
+	err := h.Handle(context.Background(), queue.Message{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
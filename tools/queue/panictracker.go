@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/devchiran/golang-demo/tools/clock"
+)
+
+// ErrPanicBudgetExceeded is returned by Consume when handler panics
+// exceed the budget configured via WithPanicBudget.
+var ErrPanicBudgetExceeded = errors.New("queue: handler panic budget exceeded")
+
+// panicTracker counts handler panics across workers in a sliding
+// window, tripping once when they exceed the budget.
+type panicTracker struct {
+	max    int
+	window time.Duration
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	times   []time.Time
+	once    sync.Once
+	tripped chan struct{}
+}
+
+func newPanicTracker(max int, window time.Duration, cl clock.Clock) *panicTracker {
+	return &panicTracker{
+		max:     max,
+		window:  window,
+		clock:   cl,
+		tripped: make(chan struct{}),
+	}
+}
+
+// record notes one panic, dropping entries that have aged out of the
+// window, and trips the tracker when the budget is exceeded.
+func (t *panicTracker) record() {
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.times[:0]
+	for _, ts := range t.times {
+		if now.Sub(ts) < t.window {
+			kept = append(kept, ts)
+		}
+	}
+	t.times = append(kept, now)
+	if len(t.times) > t.max {
+		t.once.Do(func() { close(t.tripped) })
+	}
+}
+
+func (t *panicTracker) isTripped() bool {
+	select {
+	case <-t.tripped:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/devchiran/golang-demo/tools"
+	"github.com/devchiran/golang-demo/tools/backoff"
+	"github.com/devchiran/golang-demo/tools/clock"
+)
+
+const ackTimeout = 5 * time.Second
+
+// pollMessage tracks a single in-flight message: its context, its
+// visibility timers and its final ack.
+type pollMessage struct {
+	ctx               context.Context
+	msg               Message
+	queue             Queue
+	logger            tools.Logger
+	visibilityTimeout time.Duration
+	// extendThreshold and expiryThreshold are the fractions of the
+	// visibility timeout at which the visibility is extended and the
+	// work abandoned, respectively.
+	extendThreshold float64
+	expiryThreshold float64
+	clock           clock.Clock
+	// onPanic, when set, is called for every recovered handler panic,
+	// feeding the consumer's panic budget.
+	onPanic func()
+}
+
+func (p *pollMessage) process(h Handler) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	stop := p.registerTimers(ctx, cancel)
+	defer stop()
+
+	if err := p.handle(ctx, h); err != nil {
+		// No ack: the message becomes visible again after its timeout.
+		p.logger.Error("handling message", "id", p.msg.ID, "attempts", p.msg.Attempts, "error", err.Error())
+		return
+	}
+
+	ackCtx, ackCancel := context.WithTimeout(p.ctx, ackTimeout)
+	defer ackCancel()
+	if err := p.queue.Ack(ackCtx, p.msg); err != nil {
+		p.logger.Error("acking message", "id", p.msg.ID, "error", err.Error())
+	}
+}
+
+// handle runs the handler, converting panics into errors so a bad
+// message cannot take down a worker.
+func (p *pollMessage) handle(ctx context.Context, h Handler) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if p.onPanic != nil {
+				p.onPanic()
+			}
+			err = errors.Errorf("panic: %v", rec)
+		}
+	}()
+	return h.Handle(ctx, p.msg)
+}
+
+// extendDelay is how long after fetch the visibility is first extended.
+func (p *pollMessage) extendDelay() time.Duration {
+	return time.Duration(float64(p.visibilityTimeout) * p.extendThreshold)
+}
+
+// expiryDelay is how long after fetch in-flight work is abandoned, kept
+// short of the visibility timeout so the work stops before another
+// consumer can pick the message up.
+func (p *pollMessage) expiryDelay() time.Duration {
+	return time.Duration(float64(p.visibilityTimeout) * p.expiryThreshold)
+}
+
+// registerTimers arranges for the message's visibility to be extended
+// partway through the timeout, and for the work to be abandoned shortly
+// before the message would become visible to other consumers again.
+func (p *pollMessage) registerTimers(ctx context.Context, expire context.CancelFunc) func() {
+	extendTimer := p.clock.AfterFunc(p.extendDelay(), func() {
+		p.extend(ctx)
+	})
+	expiryTimer := p.clock.AfterFunc(p.expiryDelay(), expire)
+	return func() {
+		extendTimer.Stop()
+		expiryTimer.Stop()
+	}
+}
+
+// extend pushes the visibility timeout out, retrying transient
+// failures, and re-arms itself for as long as the message is in flight.
+// Max keeps the worst-case delay at the two seconds the old linear
+// schedule allowed.
+func (p *pollMessage) extend(ctx context.Context) {
+	const maxRetries = 3
+	bo := backoff.Exponential{Base: time.Second, Max: 2 * time.Second}
+	for retries := 0; retries < maxRetries; retries++ {
+		if err := p.queue.Extend(ctx, p.msg, p.visibilityTimeout); err == nil {
+			p.clock.AfterFunc(p.extendDelay(), func() {
+				if ctx.Err() == nil {
+					p.extend(ctx)
+				}
+			})
+			return
+		}
+		if bo.Wait(ctx, retries) != nil {
+			return
+		}
+	}
+	p.logger.Error("extending message visibility", "id", p.msg.ID)
+}
@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools/clock"
+)
+
+// stubQueue records Extend calls; the other methods are unused by the
+// pollMessage tests.
+type stubQueue struct {
+	extended chan time.Time
+}
+
+func (s *stubQueue) GetMessages(context.Context, GetMessagesRequest) ([]Message, error) {
+	return nil, nil
+}
+
+func (s *stubQueue) Ack(context.Context, Message) error { return nil }
+
+func (s *stubQueue) Extend(context.Context, Message, time.Duration) error {
+	s.extended <- time.Now()
+	return nil
+}
+
+func (s *stubQueue) extendCount() int { return len(s.extended) }
+
+func TestThresholdOptionsValidated(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		opts         []ConsumerOption
+		extend, expi float64
+	}{
+		{
+			name:   "valid thresholds are applied",
+			opts:   []ConsumerOption{WithExtendThreshold(0.25), WithExpiryThreshold(0.75)},
+			extend: 0.25,
+			expi:   0.75,
+		},
+		{
+			name:   "out-of-range values are ignored",
+			opts:   []ConsumerOption{WithExtendThreshold(0), WithExpiryThreshold(1.5)},
+			extend: defaultExtendThreshold,
+			expi:   defaultExpiryThreshold,
+		},
+		{
+			name:   "extend at or past expiry falls back to defaults",
+			opts:   []ConsumerOption{WithExtendThreshold(0.8), WithExpiryThreshold(0.6)},
+			extend: defaultExtendThreshold,
+			expi:   defaultExpiryThreshold,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewConsumer(&stubQueue{}, tc.opts...)
+			if c.extendThreshold != tc.extend {
+				t.Errorf("extendThreshold = %v, want %v", c.extendThreshold, tc.extend)
+			}
+			if c.expiryThreshold != tc.expi {
+				t.Errorf("expiryThreshold = %v, want %v", c.expiryThreshold, tc.expi)
+			}
+		})
+	}
+}
+
+func TestRegisterTimersUseThresholds(t *testing.T) {
+	fc := clock.NewFake()
+	q := &stubQueue{extended: make(chan time.Time, 8)}
+	pm := &pollMessage{
+		ctx:               context.Background(),
+		msg:               Message{ID: "m1"},
+		queue:             q,
+		logger:            nopLogger{},
+		visibilityTimeout: 200 * time.Second,
+		extendThreshold:   0.25,
+		expiryThreshold:   0.6,
+		clock:             fc,
+	}
+	if got, want := pm.extendDelay(), 50*time.Second; got != want {
+		t.Fatalf("extendDelay = %v, want %v", got, want)
+	}
+	if got, want := pm.expiryDelay(), 120*time.Second; got != want {
+		t.Fatalf("expiryDelay = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := pm.registerTimers(ctx, cancel)
+	defer stop()
+
+	fc.Advance(49 * time.Second)
+	if got := q.extendCount(); got != 0 {
+		t.Fatalf("extend fired %d times before its threshold", got)
+	}
+	fc.Advance(time.Second)
+	if got := q.extendCount(); got != 1 {
+		t.Fatalf("extend fired %d times at its threshold, want 1", got)
+	}
+
+	// The extension re-arms itself at the same fraction of the timeout.
+	fc.Advance(50 * time.Second)
+	if got := q.extendCount(); got != 2 {
+		t.Fatalf("extend fired %d times after re-arming, want 2", got)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("work abandoned before the expiry threshold")
+	}
+	fc.Advance(20 * time.Second)
+	if ctx.Err() == nil {
+		t.Fatal("expected work to be abandoned at the expiry threshold")
+	}
+
+	// Once expired, the re-armed extension must not fire again.
+	fc.Advance(200 * time.Second)
+	if got := q.extendCount(); got != 2 {
+		t.Errorf("extend fired %d times after expiry, want 2", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Package queue consumes messages from a message queue with a worker
+// pool, visibility-timeout extension and per-message panic recovery.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a single queue message.
+type Message struct {
+	ID         string
+	Body       []byte
+	Attempts   int
+	Attributes map[string]string
+}
+
+// GetMessagesRequest asks the backend for a batch of messages.
+type GetMessagesRequest struct {
+	MessageCount      int
+	WaitTime          time.Duration
+	VisibilityTimeout time.Duration
+}
+
+// Queue is the backend the Consumer fetches from.
+type Queue interface {
+	GetMessages(ctx context.Context, req GetMessagesRequest) ([]Message, error)
+	// Ack removes a handled message from the queue.
+	Ack(ctx context.Context, m Message) error
+	// Extend postpones the message's visibility timeout.
+	Extend(ctx context.Context, m Message, d time.Duration) error
+}
+
+// Handler processes one message. Returning nil acks the message;
+// returning an error leaves it to become visible again.
+type Handler interface {
+	Handle(ctx context.Context, m Message) error
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(ctx context.Context, m Message) error
+
+// Handle implements Handler.
+func (f HandlerFunc) Handle(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// nopLogger is the default logger when none is configured.
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
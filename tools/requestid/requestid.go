@@ -0,0 +1,18 @@
+// Package requestid stores a per-request identifier on the context.
+package requestid
+
+import "context"
+
+type ctxKey struct{}
+
+// Set returns a child context carrying the request id.
+func Set(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// Get returns the request id stored on the context, or the empty string
+// when none is set.
+func Get(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
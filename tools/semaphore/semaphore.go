@@ -0,0 +1,128 @@
+// Package semaphore provides a counting semaphore with a bound on the
+// number of queued waiters, so overload turns into fast failures instead
+// of an ever-growing queue.
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/devchiran/golang-demo/tools"
+)
+
+// ErrTooManyQueued is returned by Acquire when the queue bound is hit.
+var ErrTooManyQueued = errors.New("semaphore: too many queued waiters")
+
+// Semaphore allows a bounded number of concurrent holders and a bounded
+// number of waiters queued behind them.
+type Semaphore struct {
+	slots chan struct{}
+	max   int64 // holders + queued
+	total int64 // current holders + queued, updated atomically
+
+	// sc, when set via WithStats, observes in-use count and wait time.
+	sc   tools.StatsClient
+	name string
+}
+
+// New returns a semaphore allowing limit concurrent holders and at most
+// queue callers waiting for a slot.
+func New(limit, queue int64) *Semaphore {
+	return &Semaphore{
+		slots: make(chan struct{}, limit),
+		max:   limit + queue,
+	}
+}
+
+// WithStats instruments the semaphore: every acquisition records how
+// long it waited as a histogram, and every acquisition and release
+// gauges the in-use count, both under name. It returns s so it can be
+// chained onto New.
+func (s *Semaphore) WithStats(sc tools.StatsClient, name string) *Semaphore {
+	s.sc = sc
+	s.name = name
+	return s
+}
+
+// Acquire obtains a single slot, blocking until one is free, the context
+// is done, or the queue bound is exceeded.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	return s.AcquireN(ctx, 1)
+}
+
+// AcquireN obtains n slots, blocking until they are free, the context is
+// done, or the queue bound is exceeded.
+func (s *Semaphore) AcquireN(ctx context.Context, n int64) error {
+	if atomic.AddInt64(&s.total, n) > s.max {
+		atomic.AddInt64(&s.total, -n)
+		return ErrTooManyQueued
+	}
+	start := time.Now()
+	for i := int64(0); i < n; i++ {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			for j := int64(0); j < i; j++ {
+				<-s.slots
+			}
+			atomic.AddInt64(&s.total, -n)
+			return ctx.Err()
+		}
+	}
+	if s.sc != nil {
+		s.sc.Histogram(s.name+".wait_seconds", time.Since(start).Seconds())
+		s.sc.Gauge(s.name+".in_use", float64(s.InUse()))
+	}
+	return nil
+}
+
+// TryAcquire obtains a single slot without blocking, reporting whether
+// it succeeded.
+func (s *Semaphore) TryAcquire() bool {
+	if atomic.AddInt64(&s.total, 1) > s.max {
+		atomic.AddInt64(&s.total, -1)
+		return false
+	}
+	select {
+	case s.slots <- struct{}{}:
+		if s.sc != nil {
+			s.sc.Gauge(s.name+".in_use", float64(s.InUse()))
+		}
+		return true
+	default:
+		atomic.AddInt64(&s.total, -1)
+		return false
+	}
+}
+
+// AcquireWithin obtains n slots like AcquireN, but waits at most d even
+// if ctx allows longer; it returns context.DeadlineExceeded when the
+// wait budget runs out.
+func (s *Semaphore) AcquireWithin(ctx context.Context, d time.Duration, n int64) error {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	return s.AcquireN(ctx, n)
+}
+
+// Release returns a single slot.
+func (s *Semaphore) Release() {
+	s.ReleaseN(1)
+}
+
+// ReleaseN returns n slots.
+func (s *Semaphore) ReleaseN(n int64) {
+	for i := int64(0); i < n; i++ {
+		<-s.slots
+	}
+	atomic.AddInt64(&s.total, -n)
+	if s.sc != nil {
+		s.sc.Gauge(s.name+".in_use", float64(s.InUse()))
+	}
+}
+
+// InUse returns the number of currently held slots.
+func (s *Semaphore) InUse() int64 {
+	return int64(len(s.slots))
+}
@@ -0,0 +1,140 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statsRecorder records gauge and histogram observations per metric.
+type statsRecorder struct {
+	mu         sync.Mutex
+	gauges     map[string][]float64
+	histograms map[string][]float64
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{
+		gauges:     map[string][]float64{},
+		histograms: map[string][]float64{},
+	}
+}
+
+func (s *statsRecorder) Count(string, float64, ...string) {}
+func (s *statsRecorder) Handler() http.Handler            { return nil }
+
+func (s *statsRecorder) Gauge(name string, value float64, _ ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gauges[name] = append(s.gauges[name], value)
+}
+
+func (s *statsRecorder) Histogram(name string, value float64, _ ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.histograms[name] = append(s.histograms[name], value)
+}
+
+func (s *statsRecorder) lastGauge(name string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vals := s.gauges[name]
+	if len(vals) == 0 {
+		return 0, false
+	}
+	return vals[len(vals)-1], true
+}
+
+func TestWithStatsRecordsWaitAndInUse(t *testing.T) {
+	sc := newStatsRecorder()
+	sem := New(2, 2).WithStats(sc, "db.semaphore")
+	ctx := context.Background()
+
+	if err := sem.Acquire(ctx); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if got, ok := sc.lastGauge("db.semaphore.in_use"); !ok || got != 1 {
+		t.Errorf("in_use after one acquire = %v (%v), want 1", got, ok)
+	}
+
+	if err := sem.Acquire(ctx); err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if got, ok := sc.lastGauge("db.semaphore.in_use"); !ok || got != 2 {
+		t.Errorf("in_use after two acquires = %v (%v), want 2", got, ok)
+	}
+
+	sem.Release()
+	sem.Release()
+	if got, ok := sc.lastGauge("db.semaphore.in_use"); !ok || got != 0 {
+		t.Errorf("in_use after releases = %v (%v), want 0", got, ok)
+	}
+
+	if got := len(sc.histograms["db.semaphore.wait_seconds"]); got != 2 {
+		t.Errorf("recorded %d wait observations, want 2", got)
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	sem := New(1, 1)
+
+	if !sem.TryAcquire() {
+		t.Fatal("expected TryAcquire to succeed on an empty semaphore")
+	}
+	if sem.TryAcquire() {
+		t.Fatal("expected TryAcquire to fail when all slots are held")
+	}
+	sem.Release()
+	if !sem.TryAcquire() {
+		t.Fatal("expected TryAcquire to succeed after a release")
+	}
+	sem.Release()
+	if got := sem.InUse(); got != 0 {
+		t.Errorf("in-use = %d, want 0", got)
+	}
+}
+
+func TestAcquireWithin(t *testing.T) {
+	sem := New(1, 1)
+	ctx := context.Background()
+
+	t.Run("immediate success", func(t *testing.T) {
+		if err := sem.AcquireWithin(ctx, time.Second, 1); err != nil {
+			t.Fatalf("AcquireWithin: %v", err)
+		}
+		sem.Release()
+	})
+
+	t.Run("timeout while full", func(t *testing.T) {
+		if err := sem.Acquire(ctx); err != nil {
+			t.Fatalf("filling semaphore: %v", err)
+		}
+		defer sem.Release()
+
+		start := time.Now()
+		err := sem.AcquireWithin(ctx, 20*time.Millisecond, 1)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("AcquireWithin waited far longer than its budget")
+		}
+	})
+
+	t.Run("queue bound still applies", func(t *testing.T) {
+		if err := sem.AcquireWithin(ctx, time.Millisecond, 10); err != ErrTooManyQueued {
+			t.Fatalf("expected ErrTooManyQueued, got %v", err)
+		}
+	})
+}
+
+func TestWithoutStatsStillWorks(t *testing.T) {
+	sem := New(1, 1)
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	sem.Release()
+}
@@ -0,0 +1,124 @@
+// Package stats implements tools.StatsClient on top of a Prometheus
+// registry.
+package stats
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Client maps the dot-and-tag StatsClient surface onto Prometheus
+// metrics. Metric names are sanitized to the Prometheus charset, and
+// "key:value" tags become labels.
+type Client struct {
+	registry *prometheus.Registry
+
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+	buckets    map[string][]float64
+}
+
+// New returns a Client with its own registry.
+func New() *Client {
+	return &Client{
+		registry:   prometheus.NewRegistry(),
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+		buckets:    map[string][]float64{},
+	}
+}
+
+// RegisterBuckets sets the buckets used for the named histogram. It
+// must be called before the first observation of that metric; once a
+// histogram exists its buckets are fixed.
+func (c *Client) RegisterBuckets(name string, buckets []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets[name] = buckets
+}
+
+// Count adds value to the named counter.
+func (c *Client) Count(name string, value float64, tags ...string) {
+	names, values := splitTags(tags)
+	c.mu.Lock()
+	v, ok := c.counters[name]
+	if !ok {
+		v = prometheus.NewCounterVec(prometheus.CounterOpts{Name: sanitize(name)}, names)
+		c.registry.MustRegister(v)
+		c.counters[name] = v
+	}
+	c.mu.Unlock()
+	v.WithLabelValues(values...).Add(value)
+}
+
+// Gauge sets the named gauge to value.
+func (c *Client) Gauge(name string, value float64, tags ...string) {
+	names, values := splitTags(tags)
+	c.mu.Lock()
+	v, ok := c.gauges[name]
+	if !ok {
+		v = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: sanitize(name)}, names)
+		c.registry.MustRegister(v)
+		c.gauges[name] = v
+	}
+	c.mu.Unlock()
+	v.WithLabelValues(values...).Set(value)
+}
+
+// Histogram observes value on the named histogram, using the buckets
+// registered via RegisterBuckets or the Prometheus defaults.
+func (c *Client) Histogram(name string, value float64, tags ...string) {
+	names, values := splitTags(tags)
+	c.mu.Lock()
+	v, ok := c.histograms[name]
+	if !ok {
+		buckets := c.buckets[name]
+		if buckets == nil {
+			buckets = prometheus.DefBuckets
+		}
+		v = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: sanitize(name), Buckets: buckets}, names)
+		c.registry.MustRegister(v)
+		c.histograms[name] = v
+	}
+	c.mu.Unlock()
+	v.WithLabelValues(values...).Observe(value)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (c *Client) Handler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
+
+// sanitize maps a dotted metric name onto the Prometheus charset.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// splitTags turns "key:value" tags into parallel label name and value
+// slices.
+func splitTags(tags []string) (names, values []string) {
+	for _, t := range tags {
+		if i := strings.IndexByte(t, ':'); i >= 0 {
+			names = append(names, t[:i])
+			values = append(values, t[i+1:])
+			continue
+		}
+		names = append(names, t)
+		values = append(values, "")
+	}
+	return names, values
+}
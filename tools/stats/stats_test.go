@@ -0,0 +1,56 @@
+package stats
+
+import "testing"
+
+func TestHistogramCustomBuckets(t *testing.T) {
+	c := New()
+	c.RegisterBuckets("req.duration_seconds", []float64{0.001, 0.01, 0.1})
+
+	c.Histogram("req.duration_seconds", 0.005, "method:GET")
+	c.Histogram("req.duration_seconds", 0.05, "method:GET")
+
+	families, err := c.registry.Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "req_duration_seconds" {
+			continue
+		}
+		buckets := mf.GetMetric()[0].GetHistogram().GetBucket()
+		counts := map[float64]uint64{}
+		for _, b := range buckets {
+			counts[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		if len(buckets) != 3 {
+			t.Fatalf("got %d buckets, want the 3 registered ones", len(buckets))
+		}
+		if counts[0.001] != 0 {
+			t.Errorf("le=0.001 count = %d, want 0", counts[0.001])
+		}
+		if counts[0.01] != 1 {
+			t.Errorf("le=0.01 count = %d, want 1", counts[0.01])
+		}
+		if counts[0.1] != 2 {
+			t.Errorf("le=0.1 count = %d, want 2", counts[0.1])
+		}
+		return
+	}
+	t.Fatal("histogram req_duration_seconds was not registered")
+}
+
+func TestHistogramDefaultBuckets(t *testing.T) {
+	c := New()
+	c.Histogram("other.duration_seconds", 0.5)
+
+	families, err := c.registry.Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "other_duration_seconds" {
+		t.Fatal("histogram other_duration_seconds was not registered")
+	}
+	if n := len(families[0].GetMetric()[0].GetHistogram().GetBucket()); n == 0 {
+		t.Error("expected the default buckets to apply")
+	}
+}
@@ -0,0 +1,88 @@
+// Package tools defines the small logging and metrics interfaces shared
+// by the tool packages, so applications can plug in their own
+// implementations without the tools depending on a concrete library.
+package tools
+
+import (
+	"context"
+	"net/http"
+)
+
+// InfoLogger logs informational messages with structured key/value pairs.
+type InfoLogger interface {
+	Info(msg string, keysAndValues ...interface{})
+}
+
+// WarnLogger logs warnings with structured key/value pairs.
+type WarnLogger interface {
+	Warn(msg string, keysAndValues ...interface{})
+}
+
+// ErrorLogger logs errors with structured key/value pairs.
+type ErrorLogger interface {
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// Logger combines the three severity interfaces.
+type Logger interface {
+	InfoLogger
+	WarnLogger
+	ErrorLogger
+}
+
+// The *Ctx variants additionally attach values derived from the context
+// (request id, trace id) to every line, depending on how the logger was
+// configured.
+
+// InfoLoggerCtx logs informational messages with context-derived fields.
+type InfoLoggerCtx interface {
+	InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+}
+
+// WarnLoggerCtx logs warnings with context-derived fields.
+type WarnLoggerCtx interface {
+	WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+}
+
+// ErrorLoggerCtx logs errors with context-derived fields.
+type ErrorLoggerCtx interface {
+	ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+}
+
+// LoggerCtx is a Logger that also supports the context-aware variants.
+type LoggerCtx interface {
+	Logger
+	InfoLoggerCtx
+	WarnLoggerCtx
+	ErrorLoggerCtx
+}
+
+// StatsClient records application metrics. Tags are "key:value" pairs.
+// Implementations must be safe for concurrent use.
+type StatsClient interface {
+	Count(name string, delta float64, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+	Histogram(name string, value float64, tags ...string)
+	// Handler exposes the collected metrics for scraping, when the
+	// backend supports it.
+	Handler() http.Handler
+}
+
+// NopStatsClient is a StatsClient that discards every observation, for
+// wiring code paths that require a client when metrics are disabled.
+type NopStatsClient struct{}
+
+func (NopStatsClient) Count(string, float64, ...string)     {}
+func (NopStatsClient) Gauge(string, float64, ...string)     {}
+func (NopStatsClient) Histogram(string, float64, ...string) {}
+func (NopStatsClient) Handler() http.Handler                { return nil }
+
+// OrNop returns sc, or NopStatsClient when sc is nil, so callers that
+// thread an optional client around do not have to nil-check at every
+// recording site.
+func OrNop(sc StatsClient) StatsClient {
+	if sc == nil {
+		return NopStatsClient{}
+	}
+	return sc
+}
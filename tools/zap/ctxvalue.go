@@ -0,0 +1,27 @@
+package zap
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+// RequestIDCtxValueFunc surfaces the request id set by the request-id
+// middleware, so every *Ctx log line carries it without the call sites
+// passing it explicitly.
+func RequestIDCtxValueFunc(ctx context.Context) (string, interface{}, bool) {
+	id := requestid.Get(ctx)
+	return "request_id", id, id != ""
+}
+
+// TraceCtxValueFunc surfaces the OpenTelemetry trace id from the span
+// context, when a span is recording on the context.
+func TraceCtxValueFunc(ctx context.Context) (string, interface{}, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "trace_id", "", false
+	}
+	return "trace_id", sc.TraceID().String(), true
+}
@@ -0,0 +1,77 @@
+package zap
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/devchiran/golang-demo/tools/requestid"
+)
+
+func newObservedLogger(funcs ...CtxValueFunc) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	return &Logger{l: zap.New(core).Sugar(), ctxFuncs: funcs}, logs
+}
+
+func loggedFields(t *testing.T, logs *observer.ObservedLogs) map[string]interface{} {
+	t.Helper()
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	return entries[0].ContextMap()
+}
+
+func TestRequestIDCtxValueFunc(t *testing.T) {
+	t.Run("present", func(t *testing.T) {
+		l, logs := newObservedLogger(RequestIDCtxValueFunc)
+		l.InfoCtx(requestid.Set(context.Background(), "req-9"), "hello")
+
+		fields := loggedFields(t, logs)
+		if fields["request_id"] != "req-9" {
+			t.Errorf("request_id = %v, want req-9", fields["request_id"])
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		l, logs := newObservedLogger(RequestIDCtxValueFunc)
+		l.InfoCtx(context.Background(), "hello")
+
+		fields := loggedFields(t, logs)
+		if _, ok := fields["request_id"]; ok {
+			t.Errorf("expected no request_id field, got %v", fields["request_id"])
+		}
+	})
+}
+
+func TestTraceCtxValueFunc(t *testing.T) {
+	t.Run("present", func(t *testing.T) {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+			SpanID:  trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+		l, logs := newObservedLogger(TraceCtxValueFunc)
+		l.InfoCtx(ctx, "hello")
+
+		fields := loggedFields(t, logs)
+		if fields["trace_id"] != sc.TraceID().String() {
+			t.Errorf("trace_id = %v, want %s", fields["trace_id"], sc.TraceID())
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		l, logs := newObservedLogger(TraceCtxValueFunc)
+		l.InfoCtx(context.Background(), "hello")
+
+		fields := loggedFields(t, logs)
+		if _, ok := fields["trace_id"]; ok {
+			t.Errorf("expected no trace_id field, got %v", fields["trace_id"])
+		}
+	})
+}
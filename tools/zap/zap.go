@@ -0,0 +1,123 @@
+// Package zap adapts go.uber.org/zap to the tools logging interfaces.
+package zap
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CtxValueFunc extracts a log field from a context. ok reports whether
+// the value is present; absent values are omitted from the line.
+type CtxValueFunc func(ctx context.Context) (key string, value interface{}, ok bool)
+
+// Config controls how the logger is built.
+type Config struct {
+	// Level is the minimum enabled level: debug, info, warn or error.
+	// Empty means info.
+	Level string
+	// Development switches to the human-friendly console encoder.
+	Development bool
+	// CtxValueFuncs are applied to the context by the *Ctx logging
+	// methods; the fields they return are attached to the line.
+	CtxValueFuncs []CtxValueFunc
+	// Sampling caps repeated entries per window. Nil logs everything.
+	Sampling *SamplingConfig
+}
+
+// SamplingConfig drops repeats of the same level and message under
+// load: the first Initial entries per Tick are logged, then one in
+// every Thereafter.
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int
+	// Tick is the sampling window. Zero means one second.
+	Tick time.Duration
+}
+
+// samplerOption wraps the logger's core in a sampler configured by sc.
+func samplerOption(sc *SamplingConfig) zap.Option {
+	tick := sc.Tick
+	if tick == 0 {
+		tick = time.Second
+	}
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, tick, sc.Initial, sc.Thereafter)
+	})
+}
+
+// Logger implements tools.LoggerCtx on top of a zap.SugaredLogger.
+type Logger struct {
+	l        *zap.SugaredLogger
+	ctxFuncs []CtxValueFunc
+}
+
+// New builds a Logger from cfg.
+func New(cfg Config) (*Logger, error) {
+	zcfg := zap.NewProductionConfig()
+	if cfg.Development {
+		zcfg = zap.NewDevelopmentConfig()
+	}
+	if cfg.Level != "" {
+		lvl, err := zapcore.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		zcfg.Level = zap.NewAtomicLevelAt(lvl)
+	}
+	opts := []zap.Option{zap.AddCallerSkip(1)}
+	if cfg.Sampling != nil {
+		opts = append(opts, samplerOption(cfg.Sampling))
+	}
+	zl, err := zcfg.Build(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{l: zl.Sugar(), ctxFuncs: cfg.CtxValueFuncs}, nil
+}
+
+// Info logs at info level.
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.l.Infow(msg, keysAndValues...)
+}
+
+// Warn logs at warn level.
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.l.Warnw(msg, keysAndValues...)
+}
+
+// Error logs at error level.
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.l.Errorw(msg, keysAndValues...)
+}
+
+func (l *Logger) ctxFields(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	for _, fn := range l.ctxFuncs {
+		if k, v, ok := fn(ctx); ok {
+			keysAndValues = append(keysAndValues, k, v)
+		}
+	}
+	return keysAndValues
+}
+
+// InfoCtx logs at info level with context-derived fields attached.
+func (l *Logger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.l.Infow(msg, l.ctxFields(ctx, keysAndValues)...)
+}
+
+// WarnCtx logs at warn level with context-derived fields attached.
+func (l *Logger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.l.Warnw(msg, l.ctxFields(ctx, keysAndValues)...)
+}
+
+// ErrorCtx logs at error level with context-derived fields attached.
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.l.Errorw(msg, l.ctxFields(ctx, keysAndValues)...)
+}
+
+// Sync flushes buffered log entries.
+func (l *Logger) Sync() error {
+	return l.l.Sync()
+}
@@ -0,0 +1,43 @@
+package zap
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplingDropsRepeats(t *testing.T) {
+	const calls = 200
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	zl := zap.New(core, samplerOption(&SamplingConfig{Initial: 5, Thereafter: 100}))
+	l := &Logger{l: zl.Sugar()}
+
+	for i := 0; i < calls; i++ {
+		l.Error("repeated failure", "attempt", i)
+	}
+
+	if got := logs.Len(); got >= calls {
+		t.Errorf("emitted %d lines for %d calls, expected sampling to drop some", got, calls)
+	}
+	if got := logs.Len(); got < 5 {
+		t.Errorf("emitted %d lines, expected at least the first 5 through", got)
+	}
+}
+
+func TestNoSamplingLogsEverything(t *testing.T) {
+	const calls = 50
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := &Logger{l: zap.New(core).Sugar()}
+
+	for i := 0; i < calls; i++ {
+		l.Info("repeated", "attempt", i)
+	}
+
+	if got := logs.Len(); got != calls {
+		t.Errorf("emitted %d lines for %d calls, want all of them", got, calls)
+	}
+}